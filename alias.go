@@ -0,0 +1,62 @@
+package magic
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	mimeNamesOnce sync.Once
+	mimeNames     map[string]string
+)
+
+// Canonical returns the canonical form of the provided MIME type, resolving legacy aliases from the freedesktop
+// shared-mime-info database (e.g. application/x-gzip becomes application/gzip). Parameters such as charset are
+// removed, and the type is matched case-insensitively, so Content-Type header values can be passed directly.
+// Unknown types are returned without their parameters but otherwise unchanged.
+func Canonical(mime string) string {
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	mime = strings.TrimSpace(mime)
+	if canonical, ok := aliases[mime]; ok {
+		return canonical
+	}
+	mimeNamesOnce.Do(buildMIMENames)
+	if name, ok := mimeNames[strings.ToLower(mime)]; ok {
+		if canonical, ok := aliases[name]; ok {
+			return canonical
+		}
+		return name
+	}
+	return mime
+}
+
+// EqualMIME reports whether the provided MIME types are the same once aliases are resolved.
+func EqualMIME(a, b string) bool {
+	return Canonical(a) == Canonical(b)
+}
+
+// buildMIMENames indexes every MIME type in the database by its lower-case form.
+func buildMIMENames() {
+	mimeNames = make(map[string]string)
+	add := func(mime string) {
+		mimeNames[strings.ToLower(mime)] = mime
+	}
+	for _, m := range dataMatchers {
+		add(m.Result.MIME)
+	}
+	for _, m := range filenameMatchers {
+		add(m.Result.MIME)
+	}
+	for mime, parents := range subClassOf {
+		add(mime)
+		for _, parent := range parents {
+			add(parent)
+		}
+	}
+	for alias, canonical := range aliases {
+		add(alias)
+		add(canonical)
+	}
+}
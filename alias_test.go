@@ -0,0 +1,56 @@
+package magic
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestCanonical(t *testing.T) {
+
+	tests := []struct {
+		mime     string
+		expected string
+	}{
+		{
+			mime:     "application/gzip",
+			expected: "application/gzip",
+		},
+		{
+			mime:     "application/x-gzip",
+			expected: "application/gzip",
+		},
+		{
+			mime:     "audio/x-wav",
+			expected: "audio/vnd.wave",
+		},
+		{
+			mime:     "application/x-javascript",
+			expected: "text/javascript",
+		},
+		{
+			mime:     "Application/X-GZIP",
+			expected: "application/gzip",
+		},
+		{
+			mime:     "text/plain; charset=utf-8",
+			expected: "text/plain",
+		},
+		{
+			mime:     "application/x-made-up",
+			expected: "application/x-made-up",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mime, func(t *testing.T) {
+			assert.Equal(t, Canonical(test.mime), test.expected)
+		})
+	}
+}
+
+func TestEqualMIME(t *testing.T) {
+	assert.Assert(t, EqualMIME("audio/x-wav", "audio/vnd.wave"))
+	assert.Assert(t, EqualMIME("application/x-gzip", "application/gzip; charset=binary"))
+	assert.Assert(t, !EqualMIME("application/gzip", "application/zip"))
+}
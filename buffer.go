@@ -0,0 +1,257 @@
+package magic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxConsecutiveEmptyReads is the number of reads returning no data and no error which are tolerated before giving
+// up on a reader, as it is probably broken.
+const maxConsecutiveEmptyReads = 100
+
+// minBufferGrowth is the smallest number of bytes by which the buffer of a bufferedReader grows. Beyond this, it
+// doubles in size each time it fills up.
+const minBufferGrowth = 512
+
+// bufferedReader holds the content read so far, so that it can be inspected by several rules.
+type bufferedReader struct {
+	reader io.Reader
+	buffer []byte
+	// limit is the maximum number of bytes to read, or zero for no limit
+	limit int
+	// exhausted is set once more content has been requested than the limit allows
+	exhausted bool
+	// err is the error which stopped reading, such as io.EOF once the end of the content has been reached
+	err error
+	// tail is the end of the content, if it can be read separately from the start. It is read from tailReader when
+	// it is first needed.
+	tail       []byte
+	tailReader *bufferedReader
+	tailLength int
+}
+
+// content is the part of some content which is available to rules: as much of the start as has been read, and the
+// end, if that is known.
+type content struct {
+	head []byte
+	tail []byte
+}
+
+// locate returns the data holding the provided offset, which is relative to the end of the content if it is
+// negative, along with the position of the offset within that data. The position is negative if the data is not
+// available.
+func (c *content) locate(offset int) ([]byte, int) {
+	if offset >= 0 {
+		return c.head, offset
+	}
+	return c.tail, len(c.tail) + offset
+}
+
+// buffer wraps r so that it can be identified within the limits of the detector, stopping reading when ctx is done.
+// The returned function releases the resources used to enforce the time limit, and must be called once
+// identification has finished.
+func (d *Detector) buffer(ctx context.Context, r io.Reader) (*bufferedReader, context.CancelFunc) {
+	ctx, cancel := d.withTimeout(ctx)
+	return d.newBufferedReader(ctx, r), cancel
+}
+
+// bufferAt is like buffer, but for content of a known size which can be read from any position. This allows the end
+// of the content to be read separately, so that rules anchored to it can be evaluated without reading everything
+// before it. Reading the end may use up to half of the read limit, leaving the rest for the start.
+func (d *Detector) bufferAt(ctx context.Context, r io.ReaderAt, size int64, tailLength int) (*bufferedReader, context.CancelFunc) {
+	ctx, cancel := d.withTimeout(ctx)
+	b := d.newBufferedReader(ctx, io.NewSectionReader(r, 0, size))
+
+	n := min(int64(tailLength), size)
+	if b.limit > 0 {
+		n = min(n, int64(b.limit/2))
+		b.limit -= int(n)
+	}
+	if n > 0 {
+		b.tailReader = d.newBufferedReader(ctx, io.NewSectionReader(r, size-n, n))
+		b.tailLength = int(n)
+	}
+	return b, cancel
+}
+
+// withTimeout applies the time limit of the detector, if it has one, to ctx.
+func (d *Detector) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, d.timeout, fmt.Errorf(
+		"%w: identification took longer than %s", ErrBudgetExhausted, d.timeout,
+	))
+}
+
+// newBufferedReader wraps r in a bufferedReader with the read limit of the detector, which stops reading when ctx
+// is done.
+func (d *Detector) newBufferedReader(ctx context.Context, r io.Reader) *bufferedReader {
+	if ctx.Done() != nil {
+		r = &contextReader{
+			ctx:    ctx,
+			reader: r,
+		}
+	}
+	return &bufferedReader{
+		reader: r,
+		limit:  d.readLimit(),
+	}
+}
+
+// bufferBytes returns a bufferedReader holding data, which is taken to be the entire content, or as much of it as
+// the read limit allows. The data is used in place rather than buffered, so the buffer limit does not apply. It is
+// returned by value so that it can live on the stack.
+func (d *Detector) bufferBytes(data []byte) bufferedReader {
+	if d.maxRead > 0 && len(data) > d.maxRead {
+		// the rest of the content is out of reach, so the end of the content is not known
+		return bufferedReader{
+			buffer:    data[:d.maxRead:d.maxRead],
+			exhausted: true,
+			err:       errPrefix,
+		}
+	}
+	return bufferedReader{
+		buffer: data[:len(data):len(data)],
+		err:    io.EOF,
+	}
+}
+
+// errPrefix stops reading content of which only the start is available.
+var errPrefix = errors.New("only the start of the content is available")
+
+// bufferPrefix is like bufferBytes, but data is only the start of the content, so the end of the content is not
+// known.
+func (d *Detector) bufferPrefix(data []byte) bufferedReader {
+	b := d.bufferBytes(data)
+	if errors.Is(b.err, io.EOF) {
+		b.err = errPrefix
+	}
+	return b
+}
+
+// readLimit returns the number of bytes the detector may read from a reader, or zero if there is no limit. Content
+// read from a reader is buffered, so this is the smaller of the read and buffer limits.
+func (d *Detector) readLimit() int {
+	limit := d.maxRead
+	if d.maxBuffer > 0 && (limit == 0 || d.maxBuffer < limit) {
+		limit = d.maxBuffer
+	}
+	return limit
+}
+
+// MaybeBuffer is like EnsureBuffered, but for callers which can make do with however much content is available.
+func (b *bufferedReader) MaybeBuffer(length int) {
+	_ = b.EnsureBuffered(length)
+}
+
+// EnsureBuffered reads until at least length bytes have been buffered. Short reads are retried, so that content
+// arriving in pieces from a pipe or network connection is buffered the same way as a local file. It returns io.EOF
+// if the content ends first, ErrBudgetExhausted if length is beyond the read limit, or the error returned by the
+// reader. Once reading has stopped, it is not resumed.
+func (b *bufferedReader) EnsureBuffered(length int) error {
+	return b.read(length, false)
+}
+
+// readAhead is like MaybeBuffer, but also buffers whatever else the reader has to hand, up to the capacity of the
+// buffer, to save reading again for the rules which look further. It does not wait for more than length bytes.
+func (b *bufferedReader) readAhead(length int) {
+	_ = b.read(length, true)
+}
+
+// read buffers length bytes for EnsureBuffered, or for readAhead if ahead is set.
+func (b *bufferedReader) read(length int, ahead bool) error {
+	if len(b.buffer) >= length {
+		return nil
+	}
+	target := length
+	if b.limit > 0 && target > b.limit {
+		target = b.limit
+	}
+
+	for empty := 0; len(b.buffer) < target && b.err == nil; {
+		if len(b.buffer) == cap(b.buffer) {
+			// the buffer grows as content arrives rather than to the length requested up front, so that a rule
+			// looking far into the content does not allocate memory for content which does not exist
+			grow := max(len(b.buffer), minBufferGrowth)
+			if !ahead {
+				grow = min(grow, target-len(b.buffer))
+			} else if b.limit > 0 {
+				grow = min(grow, b.limit-len(b.buffer))
+			}
+			b.buffer = append(make([]byte, 0, len(b.buffer)+grow), b.buffer...)
+		}
+		end := cap(b.buffer)
+		if !ahead {
+			end = min(end, target)
+		}
+		n, err := b.reader.Read(b.buffer[len(b.buffer):end])
+		b.buffer = b.buffer[:len(b.buffer)+n]
+		switch {
+		case err != nil:
+			b.err = err
+		case n > 0:
+			empty = 0
+		default:
+			if empty++; empty >= maxConsecutiveEmptyReads {
+				b.err = io.ErrNoProgress
+			}
+		}
+	}
+
+	switch {
+	case len(b.buffer) >= length:
+		return nil
+	case len(b.buffer) < target:
+		return b.err
+	default:
+		b.exhausted = true
+		return ErrBudgetExhausted
+	}
+}
+
+// readErr returns the error which stopped reading, unless reading stopped because the content ended.
+func (b *bufferedReader) readErr() error {
+	if b.err == nil || errors.Is(b.err, io.EOF) || errors.Is(b.err, errPrefix) {
+		return nil
+	}
+	return b.err
+}
+
+// Data returns the content buffered so far.
+func (b *bufferedReader) Data() []byte {
+	return b.buffer
+}
+
+// content returns the content available to rules. Once the end of the content has been reached, the whole content
+// is buffered, so it also serves as the end of the content. Otherwise, the end is read separately if possible.
+func (b *bufferedReader) content() content {
+	b.readTail()
+	return b.available()
+}
+
+// available is like content, but does not read the end of the content if it has not been read yet.
+func (b *bufferedReader) available() content {
+	if errors.Is(b.err, io.EOF) {
+		return content{head: b.buffer, tail: b.buffer}
+	}
+	return content{head: b.buffer, tail: b.tail}
+}
+
+// readTail reads the end of the content, if it can be read separately and has not been read yet.
+func (b *bufferedReader) readTail() {
+	if b.tailReader == nil {
+		return
+	}
+	// the tail is only usable if it was read in full, as otherwise it does not end at the end of the content
+	if errors.Is(b.err, io.EOF) {
+		// the whole content has been read already
+	} else if err := b.tailReader.EnsureBuffered(b.tailLength); err == nil {
+		b.tail = b.tailReader.Data()
+	} else if b.err == nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+	b.tailReader = nil
+}
@@ -0,0 +1,179 @@
+package magic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// emptyReader never returns any data, nor an error.
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) {
+	return 0, nil
+}
+
+func TestEnsureBuffered(t *testing.T) {
+	content := []byte("0123456789")
+	failure := errors.New("connection reset")
+
+	tests := []struct {
+		name        string
+		reader      io.Reader
+		limit       int
+		length      int
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "single read",
+			reader:   bytes.NewReader(content),
+			length:   4,
+			expected: "0123",
+		},
+		{
+			name:     "one byte at a time",
+			reader:   iotest.OneByteReader(bytes.NewReader(content)),
+			length:   8,
+			expected: "01234567",
+		},
+		{
+			name:     "half at a time",
+			reader:   iotest.HalfReader(bytes.NewReader(content)),
+			length:   10,
+			expected: "0123456789",
+		},
+		{
+			name:     "data with EOF",
+			reader:   iotest.DataErrReader(bytes.NewReader(content)),
+			length:   10,
+			expected: "0123456789",
+		},
+		{
+			name:        "end of content",
+			reader:      iotest.OneByteReader(bytes.NewReader(content)),
+			length:      20,
+			expected:    "0123456789",
+			expectedErr: io.EOF,
+		},
+		{
+			name:        "error after short read",
+			reader:      io.MultiReader(iotest.OneByteReader(bytes.NewReader(content[:3])), iotest.ErrReader(failure)),
+			length:      8,
+			expected:    "012",
+			expectedErr: failure,
+		},
+		{
+			name:        "timeout",
+			reader:      iotest.TimeoutReader(iotest.HalfReader(bytes.NewReader(content))),
+			length:      8,
+			expected:    "0123",
+			expectedErr: iotest.ErrTimeout,
+		},
+		{
+			name:        "no progress",
+			reader:      emptyReader{},
+			length:      8,
+			expectedErr: io.ErrNoProgress,
+		},
+		{
+			name:        "limit",
+			reader:      iotest.OneByteReader(bytes.NewReader(content)),
+			limit:       6,
+			length:      8,
+			expected:    "012345",
+			expectedErr: ErrBudgetExhausted,
+		},
+		{
+			name:     "within limit",
+			reader:   bytes.NewReader(content),
+			limit:    6,
+			length:   6,
+			expected: "012345",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &bufferedReader{reader: test.reader, limit: test.limit}
+			err := b.EnsureBuffered(test.length)
+			if test.expectedErr == nil {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, errors.Is(err, test.expectedErr), "got %v", err)
+			}
+			assert.Equal(t, string(b.Data()), test.expected)
+		})
+	}
+}
+
+func TestEnsureBufferedDoesNotResume(t *testing.T) {
+	b := &bufferedReader{reader: io.MultiReader(bytes.NewReader([]byte("01")), iotest.ErrReader(io.ErrClosedPipe), bytes.NewReader([]byte("23")))}
+	assert.Assert(t, errors.Is(b.EnsureBuffered(4), io.ErrClosedPipe))
+	assert.Assert(t, errors.Is(b.EnsureBuffered(4), io.ErrClosedPipe))
+	assert.Equal(t, string(b.Data()), "01")
+}
+
+func TestIdentifyShortReads(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+	assert.Equal(t, Identify(iotest.OneByteReader(bytes.NewReader(png))).MIME, "image/png")
+	assert.Equal(t, Identify(iotest.HalfReader(bytes.NewReader(png))).MIME, "image/png")
+
+	pr, pw := io.Pipe()
+	go func() {
+		for _, b := range png {
+			_, _ = pw.Write([]byte{b})
+		}
+		_ = pw.Close()
+	}()
+	assert.Equal(t, Identify(pr).MIME, "image/png")
+}
+
+func TestIdentifyReadsOnlyAsFarAsRulesLook(t *testing.T) {
+	d := NewDetector(RuleSet{DataMatchers: []DataMatcher{
+		{
+			Submatches: []DataSubMatcher{{Bytes: []byte("NEAR"), Offset: 0}},
+			Result:     FileType{MIME: "application/x-near"},
+			Priority:   80,
+		},
+		{
+			Submatches: []DataSubMatcher{{Bytes: []byte("FAR"), Offset: 1 << 20}},
+			Result:     FileType{MIME: "application/x-far"},
+			Priority:   50,
+		},
+	}}, WithTimeout(time.Second))
+
+	pr, pw := io.Pipe()
+	defer func() { _ = pw.Close() }()
+	go func() {
+		_, _ = pw.Write([]byte("NEAR"))
+	}()
+
+	// the rest of the content never arrives, but the rule which matches takes priority over the one looking for it
+	ft, err := d.IdentifyContext(context.Background(), pr)
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "application/x-near")
+}
+
+func TestDetectorMaxRead(t *testing.T) {
+	rules := RuleSet{
+		DataMatchers: []DataMatcher{
+			{
+				Submatches: []DataSubMatcher{{Bytes: []byte("FAR"), Offset: 64}},
+				Result:     FileType{MIME: "application/x-far"},
+				Priority:   50,
+			},
+		},
+	}
+	content := append(bytes.Repeat([]byte{0xff}, 64), "FAR"...)
+
+	assert.Equal(t, NewDetector(rules).Identify(bytes.NewReader(content)).MIME, "application/x-far")
+	assert.Equal(t, NewDetector(rules, WithMaxRead(32)).Identify(bytes.NewReader(content)).MIME, "application/octet-stream")
+}
@@ -0,0 +1,145 @@
+package magic
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MatchSource describes which kind of rule produced a Candidate.
+type MatchSource int
+
+const (
+	// MatchSourceMagic indicates the candidate was found by inspecting the content.
+	MatchSourceMagic MatchSource = iota
+	// MatchSourceGlob indicates the candidate was found by matching the filename.
+	MatchSourceGlob
+	// MatchSourceXMLRoot indicates the candidate was found by the root element of an XML document.
+	MatchSourceXMLRoot
+)
+
+func (s MatchSource) String() string {
+	switch s {
+	case MatchSourceMagic:
+		return "magic"
+	case MatchSourceGlob:
+		return "glob"
+	case MatchSourceXMLRoot:
+		return "xml-root"
+	default:
+		return "unknown"
+	}
+}
+
+// Candidate is a possible file type for some input, along with how it was found.
+type Candidate struct {
+	FileType FileType
+	// Priority is the priority of the magic rule, or the weight of the glob, that matched.
+	Priority int
+	Source   MatchSource
+	// Confidence is the priority normalised to the range [0, 1].
+	Confidence float64
+}
+
+// IdentifyAll returns every file type which matches the provided bytes. See Detector.IdentifyAll.
+func IdentifyAll(r io.Reader) []Candidate {
+	return defaultDetector.IdentifyAll(r)
+}
+
+// IdentifyAll returns every file type whose magic or root-XML rules match the provided bytes, ordered from most to
+// least confident. Unlike Identify, it does not stop at the first match, so overlapping signatures can be detected.
+// An empty slice is returned if nothing matches.
+func (d *Detector) IdentifyAll(r io.Reader) []Candidate {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	return rankCandidates(d.rules.Load().matchAllData(b))
+}
+
+// IdentifyAllWithFilename is like IdentifyAll, but also matches the filename. See Detector.IdentifyAllWithFilename.
+func IdentifyAllWithFilename(r io.Reader, filename string) []Candidate {
+	return defaultDetector.IdentifyAllWithFilename(r, filename)
+}
+
+// IdentifyAllWithFilename is like IdentifyAll, but also includes candidates found by matching the filename.
+func (d *Detector) IdentifyAllWithFilename(r io.Reader, filename string) []Candidate {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	rules := d.rules.Load()
+	candidates := rules.matchAllData(b)
+	candidates = append(candidates, rules.matchAllFilenames(filepath.Base(filename))...)
+	return rankCandidates(candidates)
+}
+
+func (rules *ruleTable) matchAllData(b *bufferedReader) []Candidate {
+	var candidates []Candidate
+	rules.dataIndex.match(b, false, func(m *DataMatcher) bool {
+		candidates = append(candidates, newCandidate(m.Result, m.Priority, MatchSourceMagic))
+		return true
+	})
+
+	m := rules.matchXMLRoot(b)
+	if m == nil {
+		return candidates
+	}
+	// like Identify, the root element refines the most confident magic match for XML, so it takes its priority and
+	// is ranked ahead of it
+	for i, candidate := range candidates {
+		if IsA(candidate.FileType.MIME, "application/xml") {
+			root := newCandidate(m.Result, candidate.Priority, MatchSourceXMLRoot)
+			return slices.Insert(candidates, i, root)
+		}
+	}
+	return append(candidates, newCandidate(m.Result, 0, MatchSourceXMLRoot))
+}
+
+func (rules *ruleTable) matchAllFilenames(filename string) []Candidate {
+	var candidates []Candidate
+	lower := strings.ToLower(filename)
+	for _, t := range rules.filenameMatchers {
+		if t.match(filename, lower) {
+			candidates = append(candidates, newCandidate(t.Result, t.Priority, MatchSourceGlob))
+		}
+	}
+	return candidates
+}
+
+func newCandidate(ft FileType, priority int, source MatchSource) Candidate {
+	confidence := float64(priority) / 100
+	if confidence > 1 {
+		confidence = 1
+	} else if confidence < 0 {
+		confidence = 0
+	}
+	return Candidate{
+		FileType:   ft,
+		Priority:   priority,
+		Source:     source,
+		Confidence: confidence,
+	}
+}
+
+// rankCandidates orders candidates by confidence, keeping only the most confident candidate for each MIME type
+// and source.
+func rankCandidates(candidates []Candidate) []Candidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+	type key struct {
+		mime   string
+		source MatchSource
+	}
+	seen := make(map[key]struct{}, len(candidates))
+	ranked := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		k := key{mime: c.FileType.MIME, source: c.Source}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		ranked = append(ranked, c)
+	}
+	return ranked
+}
@@ -0,0 +1,53 @@
+package magic
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIdentifyAll(t *testing.T) {
+	candidates := IdentifyAll(bytes.NewBufferString(`<?xml version="1.0"?><svg>`))
+	assert.Assert(t, len(candidates) >= 2)
+	assert.Equal(t, candidates[0].FileType.MIME, "image/svg+xml")
+	assert.Equal(t, candidates[0].Source, MatchSourceMagic)
+	assert.Equal(t, candidates[0].Confidence, 0.45)
+
+	var foundXML bool
+	for i, c := range candidates {
+		if i > 0 {
+			assert.Assert(t, c.Confidence <= candidates[i-1].Confidence)
+		}
+		if c.FileType.MIME == "application/xml" {
+			foundXML = true
+		}
+	}
+	assert.Assert(t, foundXML)
+}
+
+func TestIdentifyAllXMLRoot(t *testing.T) {
+	data := `<?xml version="1.0"?><gpx xmlns="http://www.topografix.com/GPX/1/1"></gpx>`
+	candidates := IdentifyAll(bytes.NewBufferString(data))
+	assert.Assert(t, len(candidates) >= 2)
+	assert.Equal(t, candidates[0].FileType.MIME, "application/gpx+xml")
+	assert.Equal(t, candidates[0].Source, MatchSourceXMLRoot)
+	assert.Equal(t, candidates[0].FileType, Identify(bytes.NewBufferString(data)))
+	assert.Equal(t, candidates[1].FileType.MIME, "application/xml")
+	assert.Equal(t, candidates[1].Confidence, candidates[0].Confidence)
+}
+
+func TestIdentifyAllNoMatch(t *testing.T) {
+	assert.Equal(t, len(IdentifyAll(bytes.NewBufferString("\x99\x99\x99\x99"))), 0)
+}
+
+func TestIdentifyAllWithFilename(t *testing.T) {
+	candidates := IdentifyAllWithFilename(bytes.NewBufferString("\x89PNG\r\n\x1a\n"), "image.jpg")
+
+	sources := make(map[string]MatchSource)
+	for _, c := range candidates {
+		sources[c.FileType.MIME] = c.Source
+	}
+	assert.Equal(t, sources["image/png"], MatchSourceMagic)
+	assert.Equal(t, sources["image/jpeg"], MatchSourceGlob)
+}
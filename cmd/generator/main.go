@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/hex"
 	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -22,13 +24,31 @@ type MimeInfo struct {
 
 type MimeType struct {
 	Type            string       `xml:"type,attr"`
-	Comment         string       `xml:"comment"`
+	Comments        []Comment    `xml:"comment"`
 	Acronym         string       `xml:"acronym"`
 	ExpandedAcronym string       `xml:"expanded-acronym"`
 	Icon            Icon         `xml:"generic-icon"`
 	Magic           []Magic      `xml:"magic"`
+	TreeMagic       []TreeMagic  `xml:"treemagic"`
+	RootXML         []RootXML    `xml:"root-XML"`
 	Globs           []Glob       `xml:"glob"`
 	SubClassOf      []SubClassOf `xml:"sub-class-of"`
+	Aliases         []Alias      `xml:"alias"`
+}
+
+type Comment struct {
+	Lang string `xml:"lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Comment returns the untranslated (English) comment.
+func (mt MimeType) Comment() string {
+	for _, c := range mt.Comments {
+		if c.Lang == "" {
+			return c.Text
+		}
+	}
+	return ""
 }
 
 type Icon struct {
@@ -39,6 +59,10 @@ type SubClassOf struct {
 	Type string `xml:"type,attr"`
 }
 
+type Alias struct {
+	Type string `xml:"type,attr"`
+}
+
 type Magic struct {
 	Priority int     `xml:"priority,attr"`
 	Matches  []Match `xml:"match"`
@@ -52,9 +76,30 @@ type Match struct {
 	Children []Match `xml:"match"`
 }
 
+type TreeMagic struct {
+	Priority int         `xml:"priority,attr"`
+	Matches  []TreeMatch `xml:"treematch"`
+}
+
+type TreeMatch struct {
+	Path       string      `xml:"path,attr"`
+	Type       string      `xml:"type,attr"`
+	MatchCase  bool        `xml:"match-case,attr"`
+	Executable bool        `xml:"executable,attr"`
+	NonEmpty   bool        `xml:"non-empty,attr"`
+	MIMEType   string      `xml:"mimetype,attr"`
+	Children   []TreeMatch `xml:"treematch"`
+}
+
+type RootXML struct {
+	NamespaceURI string `xml:"namespaceURI,attr"`
+	LocalName    string `xml:"localName,attr"`
+}
+
 type Glob struct {
-	Pattern string `xml:"pattern,attr"`
-	Weight  int    `xml:"weight,attr"`
+	Pattern       string `xml:"pattern,attr"`
+	Weight        int    `xml:"weight,attr"`
+	CaseSensitive bool   `xml:"case-sensitive,attr"`
 }
 
 func applyMask(data []byte, mask []byte) []byte {
@@ -70,32 +115,39 @@ func applyMask(data []byte, mask []byte) []byte {
 }
 
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <input.xml> <output.go>\n", os.Args[0])
+	translationsDir := flag.String(
+		"translations",
+		"",
+		"directory of shared-mime-info .po files to take translated descriptions from",
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-translations <po-dir>] <input.xml> <output.go>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	inputFile := os.Args[1]
-	outputFile := os.Args[2]
+	inputFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
 
-	// Read and parse XML
-	xmlFile, err := os.Open(inputFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", inputFile, err)
-		os.Exit(1)
-	}
-	defer func() { _ = xmlFile.Close() }()
-
-	data, err := io.ReadAll(xmlFile)
+	mimeInfo, err := readMimeInfo(inputFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", inputFile, err)
 		os.Exit(1)
 	}
 
-	var mimeInfo MimeInfo
-	if err := xml.Unmarshal(data, &mimeInfo); err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing XML: %v\n", err)
-		os.Exit(1)
+	// The source database only contains English descriptions: translations are kept in the .po files of
+	// shared-mime-info and only merged into the database when it is built and installed.
+	var catalogs map[string]map[string]string
+	if *translationsDir != "" {
+		catalogs, err = readCatalogs(*translationsDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading translations from %s: %v\n", *translationsDir, err)
+			os.Exit(1)
+		}
 	}
 
 	var dataMatchers []*magic.DataMatcher
@@ -110,10 +162,32 @@ func main() {
 		}
 	}
 
-	sort.Slice(dataMatchers, func(i, j int) bool {
+	sort.SliceStable(dataMatchers, func(i, j int) bool {
 		return dataMatchers[i].Priority > dataMatchers[j].Priority
 	})
 
+	var treeMatchers []*magic.TreeMatcher
+	for _, mt := range mimeInfo.MimeTypes {
+		for _, tm := range mt.TreeMagic {
+			treeMatchers = append(treeMatchers, buildTreeMatcher(mt, tm))
+		}
+	}
+
+	sort.SliceStable(treeMatchers, func(i, j int) bool {
+		return treeMatchers[i].Priority > treeMatchers[j].Priority
+	})
+
+	var xmlRootMatchers []*magic.XMLRootMatcher
+	for _, mt := range mimeInfo.MimeTypes {
+		for _, r := range mt.RootXML {
+			xmlRootMatchers = append(xmlRootMatchers, &magic.XMLRootMatcher{
+				NamespaceURI: r.NamespaceURI,
+				LocalName:    r.LocalName,
+				Result:       buildResult(mt),
+			})
+		}
+	}
+
 	var filenameMatchers []*magic.FilenameMatcher
 	for _, mt := range mimeInfo.MimeTypes {
 		for _, g := range mt.Globs {
@@ -127,14 +201,15 @@ func main() {
 			}
 			res.RecommendedExtension = filepath.Ext(g.Pattern)
 			filenameMatchers = append(filenameMatchers, &magic.FilenameMatcher{
-				Pattern:  g.Pattern,
-				Result:   res,
-				Priority: g.Weight,
+				Pattern:       g.Pattern,
+				Result:        res,
+				Priority:      g.Weight,
+				CaseSensitive: g.CaseSensitive,
 			})
 		}
 	}
 
-	sort.Slice(filenameMatchers, func(i, j int) bool {
+	sort.SliceStable(filenameMatchers, func(i, j int) bool {
 		return filenameMatchers[i].Priority > filenameMatchers[j].Priority
 	})
 
@@ -164,6 +239,196 @@ func main() {
 	}
 
 	_, _ = fmt.Fprintln(out, "}")
+	_, _ = fmt.Fprintln(out)
+
+	_, _ = fmt.Fprintln(out, "var treeMatchers = []TreeMatcher{")
+
+	for _, tm := range treeMatchers {
+		writeTreeMatcher(out, tm)
+	}
+
+	_, _ = fmt.Fprintln(out, "}")
+	_, _ = fmt.Fprintln(out)
+
+	_, _ = fmt.Fprintln(out, "var xmlRootMatchers = []XMLRootMatcher{")
+
+	for _, xm := range xmlRootMatchers {
+		writeXMLRootMatcher(out, xm)
+	}
+
+	_, _ = fmt.Fprintln(out, "}")
+	_, _ = fmt.Fprintln(out)
+
+	_, _ = fmt.Fprintln(out, "var subClassOf = map[string][]string{")
+
+	writeStringSlices(out, buildSubClassOf(mimeInfo))
+
+	_, _ = fmt.Fprintln(out, "}")
+	_, _ = fmt.Fprintln(out)
+
+	_, _ = fmt.Fprintln(out, "var aliases = map[string]string{")
+
+	writeStrings(out, buildAliases(mimeInfo))
+
+	_, _ = fmt.Fprintln(out, "}")
+	_, _ = fmt.Fprintln(out)
+
+	translations, untranslated := buildTranslations(mimeInfo, catalogs)
+
+	_, _ = fmt.Fprintln(out, "var descriptionTranslations = map[string]map[string]string{")
+
+	writeStringMaps(out, translations)
+
+	_, _ = fmt.Fprintln(out, "}")
+	_, _ = fmt.Fprintln(out)
+
+	_, _ = fmt.Fprintln(out, "var untranslatedDescriptions = map[string]string{")
+
+	writeStrings(out, untranslated)
+
+	_, _ = fmt.Fprintln(out, "}")
+}
+
+func readMimeInfo(path string) (MimeInfo, error) {
+	xmlFile, err := os.Open(path)
+	if err != nil {
+		return MimeInfo{}, err
+	}
+	defer func() { _ = xmlFile.Close() }()
+
+	data, err := io.ReadAll(xmlFile)
+	if err != nil {
+		return MimeInfo{}, err
+	}
+
+	var mimeInfo MimeInfo
+	if err := xml.Unmarshal(data, &mimeInfo); err != nil {
+		return MimeInfo{}, fmt.Errorf("error parsing XML: %w", err)
+	}
+	return mimeInfo, nil
+}
+
+// buildTranslations looks up the comment of each type in mimeInfo in the catalogs, which map languages to
+// translations keyed by English comment. It returns the translations of each type, keyed by language, along with
+// the English comment of each translated type.
+func buildTranslations(mimeInfo MimeInfo, catalogs map[string]map[string]string) (map[string]map[string]string, map[string]string) {
+	translations := make(map[string]map[string]string)
+	untranslated := make(map[string]string)
+	for _, mt := range mimeInfo.MimeTypes {
+		comment := mt.Comment()
+		for lang, catalog := range catalogs {
+			text, ok := catalog[comment]
+			if !ok {
+				continue
+			}
+			if translations[mt.Type] == nil {
+				translations[mt.Type] = make(map[string]string)
+				untranslated[mt.Type] = comment
+			}
+			translations[mt.Type][lang] = text
+		}
+	}
+	return translations, untranslated
+}
+
+// readCatalogs reads the .po files listed in the LINGUAS file of dir, returning the translations of each
+// language keyed by their English text. Fuzzy and empty translations are left out.
+func readCatalogs(dir string) (map[string]map[string]string, error) {
+	linguas, err := os.ReadFile(filepath.Join(dir, "LINGUAS"))
+	if err != nil {
+		return nil, err
+	}
+	catalogs := make(map[string]map[string]string)
+	for _, line := range strings.Split(string(linguas), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		for _, lang := range strings.Fields(line) {
+			catalog, err := readCatalog(filepath.Join(dir, lang+".po"))
+			if err != nil {
+				return nil, err
+			}
+			catalogs[lang] = catalog
+		}
+	}
+	if len(catalogs) == 0 {
+		return nil, errors.New("no languages are listed in LINGUAS")
+	}
+	return catalogs, nil
+}
+
+// readCatalog reads the translations from a .po file, keyed by their English text.
+func readCatalog(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	catalog := make(map[string]string)
+	var msgid, msgstr, field *strings.Builder
+	var fuzzy bool
+	flush := func() {
+		if msgid != nil && msgstr != nil && !fuzzy && msgid.Len() > 0 && msgstr.Len() > 0 {
+			catalog[msgid.String()] = msgstr.String()
+		}
+		msgid, msgstr, field, fuzzy = nil, nil, nil, false
+	}
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		keyword, quoted, _ := strings.Cut(line, " ")
+		switch {
+		case line == "":
+			flush()
+			continue
+		case strings.HasPrefix(line, "#,"):
+			fuzzy = fuzzy || strings.Contains(line, "fuzzy")
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, `"`):
+			quoted = line
+		case keyword == "msgctxt":
+			field = &strings.Builder{}
+		case keyword == "msgid":
+			if msgstr != nil {
+				flush()
+			}
+			msgid = &strings.Builder{}
+			field = msgid
+		case keyword == "msgstr":
+			msgstr = &strings.Builder{}
+			field = msgstr
+		default:
+			// plural forms are not used for descriptions
+			field = &strings.Builder{}
+		}
+		text, err := strconv.Unquote(quoted)
+		if err != nil || field == nil {
+			return nil, fmt.Errorf("%s:%d: malformed line %q", path, n+1, line)
+		}
+		field.WriteString(text)
+	}
+	flush()
+	return catalog, nil
+}
+
+func buildSubClassOf(mimeInfo MimeInfo) map[string][]string {
+	parents := make(map[string][]string)
+	for _, mt := range mimeInfo.MimeTypes {
+		for _, s := range mt.SubClassOf {
+			parents[mt.Type] = append(parents[mt.Type], s.Type)
+		}
+	}
+	return parents
+}
+
+func buildAliases(mimeInfo MimeInfo) map[string]string {
+	aliases := make(map[string]string)
+	for _, mt := range mimeInfo.MimeTypes {
+		for _, a := range mt.Aliases {
+			aliases[a.Type] = mt.Type
+		}
+	}
+	return aliases
 }
 
 func buildResult(mt MimeType) magic.FileType {
@@ -181,10 +446,12 @@ func buildResult(mt MimeType) magic.FileType {
 		}
 	}
 	return magic.FileType{
-		Description:          mt.Comment,
+		Description:          mt.Comment(),
 		RecommendedExtension: ext,
 		MIME:                 mt.Type,
 		Icon:                 mt.Icon.Name,
+		Acronym:              mt.Acronym,
+		ExpandedAcronym:      mt.ExpandedAcronym,
 	}
 }
 
@@ -226,7 +493,7 @@ func buildDataSubMatcher(match Match) (magic.DataSubMatcher, error) {
 		return magic.DataSubMatcher{}, err
 	}
 
-	offsets, err := convertOffsets(match.Offset)
+	offset, offsetRange, err := convertOffset(match.Offset)
 	if err != nil {
 		return magic.DataSubMatcher{}, err
 	}
@@ -242,12 +509,46 @@ func buildDataSubMatcher(match Match) (magic.DataSubMatcher, error) {
 
 	return magic.DataSubMatcher{
 		Bytes:    b,
-		Offsets:  offsets,
+		Offset:   offset,
+		Range:    offsetRange,
 		Mask:     mask,
 		Children: children,
 	}, nil
 }
 
+func buildTreeMatcher(mt MimeType, m TreeMagic) *magic.TreeMatcher {
+	matcher := &magic.TreeMatcher{
+		Priority: m.Priority,
+		Result:   buildResult(mt),
+	}
+
+	if matcher.Priority == 0 {
+		matcher.Priority = 50
+	}
+
+	for _, match := range m.Matches {
+		matcher.Submatches = append(matcher.Submatches, buildTreeSubMatcher(match))
+	}
+
+	return matcher
+}
+
+func buildTreeSubMatcher(match TreeMatch) magic.TreeSubMatcher {
+	var children []magic.TreeSubMatcher
+	for _, child := range match.Children {
+		children = append(children, buildTreeSubMatcher(child))
+	}
+	return magic.TreeSubMatcher{
+		Path:       match.Path,
+		Type:       match.Type,
+		MatchCase:  match.MatchCase,
+		Executable: match.Executable,
+		NonEmpty:   match.NonEmpty,
+		MIMEType:   match.MIMEType,
+		Children:   children,
+	}
+}
+
 func decodeOctalBytes(s string) ([]byte, error) {
 	var result []byte
 	// Process in groups of 3 digits (or remaining digits)
@@ -278,32 +579,32 @@ func convertMask(mask string) ([]byte, error) {
 	}
 }
 
-func convertOffsets(offset string) ([]int, error) {
+// convertOffset parses an offset such as "4" or "0:256", returning the start and the number of further positions
+// covered by the range.
+func convertOffset(offset string) (int, int, error) {
 
 	if offset == "" {
-		return []int{0}, nil
+		return 0, 0, nil
 	}
 
 	start, end, ok := strings.Cut(offset, ":")
 	startInt, err := strconv.Atoi(start)
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
 	if !ok {
-		return []int{startInt}, nil
+		return startInt, 0, nil
 	}
 
 	endInt, err := strconv.Atoi(end)
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
-
-	var output []int
-	for i := startInt; i <= endInt; i++ {
-		output = append(output, i)
+	if endInt < startInt {
+		return 0, 0, fmt.Errorf("invalid offset range: %s", offset)
 	}
 
-	return output, nil
+	return startInt, endInt - startInt, nil
 }
 
 func convertValue(value string, valueType string) ([]byte, error) {
@@ -451,6 +752,9 @@ func writeFilenameMatcher(out io.Writer, entry *magic.FilenameMatcher) {
 		indent := indent + indentStep
 		_, _ = fmt.Fprintf(out, "%sPattern:  %q,\n", indent, entry.Pattern)
 		_, _ = fmt.Fprintf(out, "%sPriority: %d,\n", indent, entry.Priority)
+		if entry.CaseSensitive {
+			_, _ = fmt.Fprintf(out, "%sCaseSensitive: true,\n", indent)
+		}
 		_, _ = fmt.Fprintf(out, "%sResult:   ", indent)
 		writeResult(out, entry.Result, indent)
 	}
@@ -483,9 +787,10 @@ func writeDataSubMatcher(out io.Writer, entry *magic.DataSubMatcher, indent stri
 		_, _ = fmt.Fprintf(out, "%sBytes:    ", indent)
 		writeBytes(out, entry.Bytes)
 		_, _ = fmt.Fprint(out, ",\n")
-		_, _ = fmt.Fprintf(out, "%sOffsets:  ", indent)
-		writeInts(out, entry.Offsets)
-		_, _ = fmt.Fprint(out, ",\n")
+		_, _ = fmt.Fprintf(out, "%sOffset:   %d,\n", indent, entry.Offset)
+		if entry.Range > 0 {
+			_, _ = fmt.Fprintf(out, "%sRange:    %d,\n", indent, entry.Range)
+		}
 		if len(entry.Mask) > 0 {
 			_, _ = fmt.Fprintf(out, "%sMask:     ", indent)
 			writeBytes(out, entry.Mask)
@@ -502,6 +807,70 @@ func writeDataSubMatcher(out io.Writer, entry *magic.DataSubMatcher, indent stri
 	_, _ = fmt.Fprintf(out, "%s},\n", indent)
 }
 
+func writeTreeMatcher(out io.Writer, entry *magic.TreeMatcher) {
+	indent := indentStep
+
+	_, _ = fmt.Fprintf(out, "%s{\n", indent)
+	{
+		indent := indent + indentStep
+		_, _ = fmt.Fprintf(out, "%sSubmatches: []TreeSubMatcher{\n", indent)
+		for _, child := range entry.Submatches {
+			writeTreeSubMatcher(out, &child, indent+indentStep)
+		}
+		_, _ = fmt.Fprintf(out, "%s},\n", indent)
+		_, _ = fmt.Fprintf(out, "%sPriority: %d,\n", indent, entry.Priority)
+		_, _ = fmt.Fprintf(out, "%sResult: ", indent)
+		writeResult(out, entry.Result, indent)
+	}
+	_, _ = fmt.Fprintf(out, "%s},\n", indent)
+}
+
+func writeTreeSubMatcher(out io.Writer, entry *magic.TreeSubMatcher, indent string) {
+
+	_, _ = fmt.Fprintf(out, "%s{\n", indent)
+	{
+		indent := indent + indentStep
+		_, _ = fmt.Fprintf(out, "%sPath:       %q,\n", indent, entry.Path)
+		if entry.Type != "" {
+			_, _ = fmt.Fprintf(out, "%sType:       %q,\n", indent, entry.Type)
+		}
+		if entry.MatchCase {
+			_, _ = fmt.Fprintf(out, "%sMatchCase:  true,\n", indent)
+		}
+		if entry.Executable {
+			_, _ = fmt.Fprintf(out, "%sExecutable: true,\n", indent)
+		}
+		if entry.NonEmpty {
+			_, _ = fmt.Fprintf(out, "%sNonEmpty:   true,\n", indent)
+		}
+		if entry.MIMEType != "" {
+			_, _ = fmt.Fprintf(out, "%sMIMEType:   %q,\n", indent, entry.MIMEType)
+		}
+		if len(entry.Children) > 0 {
+			_, _ = fmt.Fprintf(out, "%sChildren: []TreeSubMatcher{\n", indent)
+			for _, child := range entry.Children {
+				writeTreeSubMatcher(out, &child, indent+indentStep)
+			}
+			_, _ = fmt.Fprintf(out, "%s},\n", indent)
+		}
+	}
+	_, _ = fmt.Fprintf(out, "%s},\n", indent)
+}
+
+func writeXMLRootMatcher(out io.Writer, entry *magic.XMLRootMatcher) {
+	indent := indentStep
+
+	_, _ = fmt.Fprintf(out, "%s{\n", indent)
+	{
+		indent := indent + indentStep
+		_, _ = fmt.Fprintf(out, "%sNamespaceURI: %q,\n", indent, entry.NamespaceURI)
+		_, _ = fmt.Fprintf(out, "%sLocalName:    %q,\n", indent, entry.LocalName)
+		_, _ = fmt.Fprintf(out, "%sResult: ", indent)
+		writeResult(out, entry.Result, indent)
+	}
+	_, _ = fmt.Fprintf(out, "%s},\n", indent)
+}
+
 func writeResult(out io.Writer, result magic.FileType, indent string) {
 	_, _ = fmt.Fprintf(out, "%sFileType{\n", indent)
 	{
@@ -520,24 +889,68 @@ func writeResult(out io.Writer, result magic.FileType, indent string) {
 		_, _ = fmt.Fprintf(out, "%sRecommendedExtension: %q,\n", indent, result.RecommendedExtension)
 		_, _ = fmt.Fprintf(out, "%sIcon:                 %q,\n", indent, icon)
 		_, _ = fmt.Fprintf(out, "%sMIME:                 %q,\n", indent, result.MIME)
+		if result.Acronym != "" {
+			_, _ = fmt.Fprintf(out, "%sAcronym:              %q,\n", indent, result.Acronym)
+		}
+		if result.ExpandedAcronym != "" {
+			_, _ = fmt.Fprintf(out, "%sExpandedAcronym:      %q,\n", indent, result.ExpandedAcronym)
+		}
 	}
 	_, _ = fmt.Fprintf(out, "%s},\n", indent)
 }
 
-func writeBytes(out io.Writer, b []byte) {
-	_, _ = fmt.Fprint(out, "[]byte{")
-	for i, v := range b {
-		if i == len(b)-1 {
-			_, _ = fmt.Fprintf(out, "%d", v)
-		} else {
-			_, _ = fmt.Fprintf(out, "%d, ", v)
+func writeStrings(out io.Writer, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(out, "%s%q: %q,\n", indentStep, k, m[k])
+	}
+}
+
+func writeStringMaps(out io.Writer, m map[string]map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(out, "%s%q: {\n", indentStep, k)
+		inner := make([]string, 0, len(m[k]))
+		for ik := range m[k] {
+			inner = append(inner, ik)
+		}
+		sort.Strings(inner)
+		for _, ik := range inner {
+			_, _ = fmt.Fprintf(out, "%s%q: %q,\n", indentStep+indentStep, ik, m[k][ik])
 		}
+		_, _ = fmt.Fprintf(out, "%s},\n", indentStep)
 	}
-	_, _ = fmt.Fprint(out, "}")
 }
 
-func writeInts(out io.Writer, b []int) {
-	_, _ = fmt.Fprint(out, "[]int{")
+func writeStringSlices(out io.Writer, m map[string][]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(out, "%s%q: {", indentStep, k)
+		for i, v := range m[k] {
+			if i == len(m[k])-1 {
+				_, _ = fmt.Fprintf(out, "%q", v)
+			} else {
+				_, _ = fmt.Fprintf(out, "%q, ", v)
+			}
+		}
+		_, _ = fmt.Fprint(out, "},\n")
+	}
+}
+
+func writeBytes(out io.Writer, b []byte) {
+	_, _ = fmt.Fprint(out, "[]byte{")
 	for i, v := range b {
 		if i == len(b)-1 {
 			_, _ = fmt.Fprintf(out, "%d", v)
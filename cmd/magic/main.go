@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,11 +10,14 @@ import (
 )
 
 func main() {
-	if len(os.Args) != 2 {
+	lang := flag.String("lang", defaultLang(), "language to show descriptions in, e.g. de or pt-BR")
+	flag.Usage = showUsageAndExit
+	flag.Parse()
+	if flag.NArg() != 1 {
 		showUsageAndExit()
 	}
 
-	filename := os.Args[1]
+	filename := flag.Arg(0)
 
 	ft, err := magic.IdentifyPath(filename)
 	if err != nil {
@@ -22,12 +26,29 @@ func main() {
 	}
 
 	fmt.Printf("File         \x1b[33m%s\x1b[0m\n", filepath.Base(filename))
-	fmt.Printf("Description  \x1b[33m%s\x1b[0m\n", ft.Description)
+	fmt.Printf("Description  \x1b[33m%s\x1b[0m\n", ft.DescriptionFor(*lang))
+	if ft.Acronym != "" {
+		acronym := ft.Acronym
+		if ft.ExpandedAcronym != "" {
+			acronym = fmt.Sprintf("%s (%s)", ft.Acronym, ft.ExpandedAcronym)
+		}
+		fmt.Printf("Acronym      \x1b[33m%s\x1b[0m\n", acronym)
+	}
 	fmt.Printf("MIME         \x1b[33m%s\x1b[0m\n", ft.MIME)
 	fmt.Printf("Icon         \x1b[33m%s\x1b[0m\n", ft.Icon)
 }
 
+// defaultLang returns the language of the current locale, using the same precedence as gettext.
+func defaultLang() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 func showUsageAndExit() {
-	fmt.Println("Usage: magic <filename>")
+	fmt.Println("Usage: magic [--lang <language>] <filename>")
 	os.Exit(1)
 }
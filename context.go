@@ -0,0 +1,105 @@
+package magic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUnknownType is returned when the content matches no rules, and so could only be identified as generic text or
+// binary data.
+var ErrUnknownType = errors.New("unknown file type")
+
+// IdentifyContext is like Identify, but returns an error if identification fails. See Detector.IdentifyContext.
+func IdentifyContext(ctx context.Context, r io.Reader) (FileType, error) {
+	return defaultDetector.IdentifyContext(ctx, r)
+}
+
+// IdentifyContext is like Identify, but returns an error if the content cannot be read, and ErrUnknownType if it
+// matches no rules. In both cases the type identified from whatever content was read is returned too. If ctx is
+// done while waiting for r, the context's error is returned without waiting for the read to finish, and r should
+// not be used again. If the content matches no rules within the detector's read limits, or the detector's time
+// limit is reached, the error is ErrBudgetExhausted.
+func (d *Detector) IdentifyContext(ctx context.Context, r io.Reader) (FileType, error) {
+	b, done := d.buffer(ctx, r)
+	defer done()
+	ft := d.rules.Load().identify(b, nil)
+	return ft, identifyErr(b, ft)
+}
+
+// IdentifyWithFilenameContext is like IdentifyWithFilename, but returns an error if identification fails. See
+// Detector.IdentifyWithFilenameContext.
+func IdentifyWithFilenameContext(ctx context.Context, r io.Reader, filename string) (FileType, error) {
+	return defaultDetector.IdentifyWithFilenameContext(ctx, r, filename)
+}
+
+// IdentifyWithFilenameContext is like IdentifyWithFilename, but returns errors in the same way as IdentifyContext.
+// With WithLegacyFilenameMatching, the content is only read if the filename alone is not enough to identify the type.
+func (d *Detector) IdentifyWithFilenameContext(ctx context.Context, r io.Reader, filename string) (FileType, error) {
+	b, done := d.buffer(ctx, r)
+	defer done()
+	ft := d.identifyWithFilename(d.rules.Load(), b, filename, nil)
+	return ft, identifyErr(b, ft)
+}
+
+// identifyErr returns the error, if any, which should accompany the type identified from the provided content.
+func identifyErr(b *bufferedReader, ft FileType) error {
+	if err := contentErr(b, ft); err != nil {
+		return err
+	}
+	if ft == unknownBinaryFileType || ft == unknownTextFileType {
+		return ErrUnknownType
+	}
+	return nil
+}
+
+// contentErr is like identifyErr, but does not treat content which matches no rules as an error, unless a limit was
+// reached before any rule matched. It is used by the functions which only fail if the content could not be read.
+func contentErr(b *bufferedReader, ft FileType) error {
+	if err := b.readErr(); err != nil {
+		return err
+	}
+	if b.exhausted && (ft == unknownBinaryFileType || ft == unknownTextFileType) {
+		return fmt.Errorf("%w: no match within the first %d bytes", ErrBudgetExhausted, len(b.buffer))
+	}
+	return nil
+}
+
+// contextReader reads from another reader until its context is done, even if a read is in progress. Each read
+// happens in its own goroutine, into a scratch buffer which is abandoned along with the goroutine if the context is
+// done first.
+type contextReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	scratch []byte
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, context.Cause(r.ctx)
+	}
+	if cap(r.scratch) < len(p) {
+		r.scratch = make([]byte, len(p))
+	}
+	scratch := r.scratch[:len(p)]
+
+	done := make(chan readResult, 1)
+	go func() {
+		n, err := r.reader.Read(scratch)
+		done <- readResult{n: n, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return copy(p, scratch[:result.n]), result.err
+	case <-r.ctx.Done():
+		r.scratch = nil
+		return 0, context.Cause(r.ctx)
+	}
+}
@@ -0,0 +1,115 @@
+package magic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestIdentifyContext(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	failure := errors.New("connection reset")
+
+	tests := []struct {
+		name         string
+		reader       io.Reader
+		expectedMIME string
+		expectedErr  error
+	}{
+		{
+			name:         "identified",
+			reader:       bytes.NewReader(png),
+			expectedMIME: "image/png",
+		},
+		{
+			name:         "unknown binary",
+			reader:       bytes.NewReader([]byte("\x99\x99\x99\x99")),
+			expectedMIME: "application/octet-stream",
+			expectedErr:  ErrUnknownType,
+		},
+		{
+			name:         "unknown text",
+			reader:       bytes.NewReader([]byte("hello")),
+			expectedMIME: "text/plain",
+			expectedErr:  ErrUnknownType,
+		},
+		{
+			name:         "empty",
+			reader:       bytes.NewReader(nil),
+			expectedMIME: "text/plain",
+			expectedErr:  ErrUnknownType,
+		},
+		{
+			name:         "truncated",
+			reader:       io.MultiReader(bytes.NewReader(png), iotest.ErrReader(failure)),
+			expectedMIME: "image/png",
+			expectedErr:  failure,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ft, err := IdentifyContext(context.Background(), test.reader)
+			if test.expectedErr == nil {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, errors.Is(err, test.expectedErr), "got %v", err)
+			}
+			assert.Equal(t, ft.MIME, test.expectedMIME)
+		})
+	}
+}
+
+func TestIdentifyContextCancelled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer func() { _ = pw.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// the pipe is never written to, so the read blocks until the deadline
+	_, err := IdentifyContext(ctx, pr)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = IdentifyContext(ctx, bytes.NewReader([]byte("\x89PNG\r\n\x1a\n")))
+	assert.Assert(t, errors.Is(err, context.Canceled), "got %v", err)
+}
+
+func TestIdentifyContextSlowReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ft, err := IdentifyContext(ctx, iotest.OneByteReader(bytes.NewReader([]byte("%PDF-1.7\n"))))
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "application/pdf")
+}
+
+func TestIdentifyWithFilenameContext(t *testing.T) {
+	ctx := context.Background()
+
+	// the content is read to check it against the filename, but the filename still suggests a type
+	ft, err := IdentifyWithFilenameContext(ctx, iotest.ErrReader(io.ErrClosedPipe), "image.png")
+	assert.Assert(t, errors.Is(err, io.ErrClosedPipe), "got %v", err)
+	assert.Equal(t, ft.MIME, "image/png")
+
+	// the filename is enough, so the content is never read
+	legacy := NewDetector(DefaultRules(), WithLegacyFilenameMatching())
+	ft, err = legacy.IdentifyWithFilenameContext(ctx, iotest.ErrReader(io.ErrClosedPipe), "image.png")
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "image/png")
+
+	ft, err = IdentifyWithFilenameContext(ctx, iotest.ErrReader(io.ErrClosedPipe), "unknown")
+	assert.Assert(t, errors.Is(err, io.ErrClosedPipe), "got %v", err)
+	assert.Equal(t, ft.MIME, "text/plain")
+
+	_, err = IdentifyWithFilenameContext(ctx, bytes.NewReader([]byte("\x99\x99")), "unknown")
+	assert.Assert(t, errors.Is(err, ErrUnknownType), "got %v", err)
+}
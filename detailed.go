@@ -0,0 +1,76 @@
+package magic
+
+import (
+	"context"
+	"io"
+)
+
+// DetailedResult describes how IdentifyWithFilename weighed the filename against the content.
+type DetailedResult struct {
+	// FileType is the type IdentifyWithFilename returns.
+	FileType FileType
+	// Filename is the type suggested by the filename, or nil if no pattern matches it. If patterns for several
+	// types match, it is the type of the first of them.
+	Filename *FileType
+	// Content is the type identified from the content alone, which is generic text or binary data if no rule
+	// matches it.
+	Content FileType
+	// UsedFilename is true if FileType was taken from the filename rather than the content.
+	UsedFilename bool
+	// Reason explains why the filename or the content was used.
+	Reason string
+	// Conflict is true if the content rules out every type suggested by the filename. The content agrees with a
+	// type if it is that type or a more general one, such as a zip archive named like a word processor document,
+	// or generic text named like source code. Binary content which matches no rule conflicts with types which have
+	// magic rules, as it would have matched them. A filename which matches no pattern never conflicts.
+	Conflict bool
+}
+
+// IdentifyWithFilenameDetailed is like IdentifyWithFilename, but also reports the types suggested by the filename
+// and the content separately. See Detector.IdentifyWithFilenameDetailed.
+func IdentifyWithFilenameDetailed(r io.Reader, filename string) DetailedResult {
+	return defaultDetector.IdentifyWithFilenameDetailed(r, filename)
+}
+
+// IdentifyWithFilenameDetailed is like IdentifyWithFilename, but also reports the types suggested by the filename
+// and the content separately, which of them was used and why, and whether they conflict. The content is read even
+// with WithLegacyFilenameMatching, which would otherwise only read it if the filename matches no single type.
+func (d *Detector) IdentifyWithFilenameDetailed(r io.Reader, filename string) DetailedResult {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	rules := d.rules.Load()
+
+	var e Explanation
+	result := DetailedResult{
+		FileType: d.identifyWithFilename(rules, b, filename, &e),
+		Content:  rules.identify(b, nil),
+		Reason:   e.Reason,
+	}
+	result.UsedFilename = e.Winner != nil && e.Winner.Source == MatchSourceGlob
+
+	globs := rules.matchFilename(filename)
+	if len(globs) == 0 {
+		return result
+	}
+	result.Filename = &globs[0].Result
+
+	// the filename agrees with the content if any of the types it suggests do
+	candidates := rules.matchAllData(b)
+	result.Conflict = true
+	for _, mime := range uniqueMIMEs(globs) {
+		if rules.filenameAgrees(mime, result.Content, candidates) {
+			result.Conflict = false
+			break
+		}
+	}
+	return result
+}
+
+// filenameAgrees is like extensionAgrees, but also accepts content identified as a more general type than mime, as
+// the content rules cannot always tell a format apart from the one it is built on.
+func (rules *ruleTable) filenameAgrees(mime string, detected FileType, candidates []Candidate) bool {
+	if rules.extensionAgrees(mime, detected, candidates) {
+		return true
+	}
+	return detected != unknownBinaryFileType && IsA(mime, detected.MIME)
+}
@@ -0,0 +1,103 @@
+package magic
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIdentifyWithFilenameDetailed(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		data             string
+		filename         string
+		expectedMIME     string
+		expectedFilename string
+		expectedContent  string
+		usedFilename     bool
+		conflict         bool
+	}{
+		{
+			name:             "zip named as pdf",
+			data:             "PK\x03\x04",
+			filename:         "report.pdf",
+			expectedMIME:     "application/pdf",
+			expectedFilename: "application/pdf",
+			expectedContent:  "application/zip",
+			usedFilename:     true,
+			conflict:         true,
+		},
+		{
+			name:             "pdf",
+			data:             "%PDF-1.7\n",
+			filename:         "report.pdf",
+			expectedMIME:     "application/pdf",
+			expectedFilename: "application/pdf",
+			expectedContent:  "application/pdf",
+			usedFilename:     true,
+		},
+		{
+			name:             "unknown binary named as pdf",
+			data:             "\x99\x99\x99\x99",
+			filename:         "report.pdf",
+			expectedMIME:     "application/pdf",
+			expectedFilename: "application/pdf",
+			expectedContent:  "application/octet-stream",
+			usedFilename:     true,
+			conflict:         true,
+		},
+		{
+			name:             "zip named as word processor document",
+			data:             "PK\x03\x04\x14\x00",
+			filename:         "letter.docx",
+			expectedMIME:     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			expectedFilename: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			expectedContent:  "application/zip",
+			usedFilename:     true,
+		},
+		{
+			name:             "text named as source code",
+			data:             "print(1)\n",
+			filename:         "main.py",
+			expectedMIME:     "text/x-python",
+			expectedFilename: "text/x-python",
+			expectedContent:  "text/plain",
+			usedFilename:     true,
+		},
+		{
+			name:             "several types match the filename",
+			data:             "PK\x03\x04",
+			filename:         "Makefile",
+			expectedMIME:     "application/zip",
+			expectedFilename: "text/x-makefile",
+			expectedContent:  "application/zip",
+			conflict:         true,
+		},
+		{
+			name:            "no pattern matches the filename",
+			data:            "\x89PNG\r\n\x1a\n",
+			filename:        "image",
+			expectedMIME:    "image/png",
+			expectedContent: "image/png",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := IdentifyWithFilenameDetailed(bytes.NewBufferString(test.data), test.filename)
+			assert.Equal(t, result.FileType, IdentifyWithFilename(bytes.NewBufferString(test.data), test.filename))
+			assert.Equal(t, result.FileType.MIME, test.expectedMIME)
+			if test.expectedFilename == "" {
+				assert.Assert(t, result.Filename == nil)
+			} else {
+				assert.Equal(t, result.Filename.MIME, test.expectedFilename)
+			}
+			assert.Equal(t, result.Content.MIME, test.expectedContent)
+			assert.Equal(t, result.UsedFilename, test.usedFilename)
+			assert.Equal(t, result.Conflict, test.conflict)
+			assert.Assert(t, result.Reason != "")
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package magic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// ErrInvalidRule is returned when registering a rule which could never match, or which cannot be evaluated.
+var ErrInvalidRule = errors.New("invalid rule")
+
+// RuleSet is the collection of rules a Detector uses to identify file types.
+type RuleSet struct {
+	DataMatchers     []DataMatcher
+	FilenameMatchers []FilenameMatcher
+	TreeMatchers     []TreeMatcher
+	XMLRootMatchers  []XMLRootMatcher
+}
+
+// DefaultRules returns the rules used by the package-level functions: every signature from the freedesktop
+// shared-mime-info database, plus a few extras.
+func DefaultRules() RuleSet {
+	rules := RuleSet{
+		DataMatchers:     make([]DataMatcher, 0, len(dataMatchers)+len(extraDataMatchers)),
+		FilenameMatchers: make([]FilenameMatcher, 0, len(filenameMatchers)+len(extraFileMatchers)),
+	}
+	rules.DataMatchers = append(append(rules.DataMatchers, dataMatchers...), extraDataMatchers...)
+	rules.FilenameMatchers = append(append(rules.FilenameMatchers, filenameMatchers...), extraFileMatchers...)
+	rules.TreeMatchers = append(rules.TreeMatchers, treeMatchers...)
+	rules.XMLRootMatchers = append(rules.XMLRootMatchers, xmlRootMatchers...)
+
+	// the origin is recorded here rather than in the generated rules, to keep them small
+	for i := range rules.DataMatchers {
+		rules.DataMatchers[i].Origin = RuleOriginFreedesktop
+		if i >= len(dataMatchers) {
+			rules.DataMatchers[i].Origin = RuleOriginExtra
+		}
+	}
+	for i := range rules.FilenameMatchers {
+		rules.FilenameMatchers[i].Origin = RuleOriginFreedesktop
+		if i >= len(filenameMatchers) {
+			rules.FilenameMatchers[i].Origin = RuleOriginExtra
+		}
+	}
+	for i := range rules.XMLRootMatchers {
+		rules.XMLRootMatchers[i].Origin = RuleOriginFreedesktop
+	}
+	return rules
+}
+
+// Detector identifies file types using its own set of rules. The package-level functions use a Detector
+// configured with DefaultRules.
+//
+// A Detector is safe for concurrent use, including registering new rules while other goroutines are
+// identifying files.
+type Detector struct {
+	mu    sync.Mutex // held while registering rules
+	rules atomic.Pointer[ruleTable]
+
+	maxRead   int           // see WithMaxRead
+	maxBuffer int           // see WithMaxBuffer
+	timeout   time.Duration // see WithTimeout
+
+	legacyFilenameMatching bool // see WithLegacyFilenameMatching
+	noFollowSymlinks       bool // see WithoutFollowingSymlinks
+}
+
+// ruleTable is an immutable snapshot of the rules belonging to a Detector, sorted by descending priority.
+type ruleTable struct {
+	dataMatchers     []DataMatcher
+	dataIndex        *dataIndex
+	filenameMatchers []FilenameMatcher
+	filenameIndex    *filenameIndex
+	treeMatchers     []TreeMatcher
+	xmlRootMatchers  []XMLRootMatcher
+}
+
+var defaultDetector = NewDetector(DefaultRules())
+
+// NewDetector creates a Detector which identifies file types using the provided rules.
+func NewDetector(rules RuleSet, opts ...Option) *Detector {
+	table := &ruleTable{
+		dataMatchers:     append([]DataMatcher(nil), rules.DataMatchers...),
+		filenameMatchers: append([]FilenameMatcher(nil), rules.FilenameMatchers...),
+		treeMatchers:     append([]TreeMatcher(nil), rules.TreeMatchers...),
+		xmlRootMatchers:  append([]XMLRootMatcher(nil), rules.XMLRootMatchers...),
+	}
+	sort.SliceStable(table.dataMatchers, func(i, j int) bool {
+		return table.dataMatchers[i].Priority > table.dataMatchers[j].Priority
+	})
+	sort.SliceStable(table.filenameMatchers, func(i, j int) bool {
+		return table.filenameMatchers[i].Priority > table.filenameMatchers[j].Priority
+	})
+	sort.SliceStable(table.treeMatchers, func(i, j int) bool {
+		return table.treeMatchers[i].Priority > table.treeMatchers[j].Priority
+	})
+	table.dataIndex = newDataIndex(table.dataMatchers)
+	table.filenameIndex = newFilenameIndex(table.filenameMatchers)
+	d := &Detector{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.rules.Store(table)
+	return d
+}
+
+// Register adds a rule to the default detector. See Detector.Register.
+func Register(m DataMatcher) error {
+	return defaultDetector.Register(m)
+}
+
+// RegisterFilename adds a filename rule to the default detector. See Detector.RegisterFilename.
+func RegisterFilename(m FilenameMatcher) error {
+	return defaultDetector.RegisterFilename(m)
+}
+
+// Register adds a rule for identifying files by their content. The rule is evaluated after any existing rules
+// of the same or higher priority, and before any rules of lower priority. If it matches along with other rules of
+// the same priority, the most specific of them is used, as described for Identify.
+func (d *Detector) Register(m DataMatcher) error {
+	if err := validateDataMatcher(m); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	old := d.rules.Load()
+	i := sort.Search(len(old.dataMatchers), func(i int) bool {
+		return old.dataMatchers[i].Priority < m.Priority
+	})
+	table := &ruleTable{
+		dataMatchers:     make([]DataMatcher, 0, len(old.dataMatchers)+1),
+		filenameMatchers: old.filenameMatchers,
+		filenameIndex:    old.filenameIndex,
+		treeMatchers:     old.treeMatchers,
+		xmlRootMatchers:  old.xmlRootMatchers,
+	}
+	table.dataMatchers = append(table.dataMatchers, old.dataMatchers[:i]...)
+	table.dataMatchers = append(table.dataMatchers, m)
+	table.dataMatchers = append(table.dataMatchers, old.dataMatchers[i:]...)
+	table.dataIndex = newDataIndex(table.dataMatchers)
+	d.rules.Store(table)
+	return nil
+}
+
+// RegisterFilename adds a rule for identifying files by their name. The rule is evaluated after any existing
+// rules of the same or higher priority, and before any rules of lower priority.
+func (d *Detector) RegisterFilename(m FilenameMatcher) error {
+	if err := validateFilenameMatcher(m); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	old := d.rules.Load()
+	i := sort.Search(len(old.filenameMatchers), func(i int) bool {
+		return old.filenameMatchers[i].Priority < m.Priority
+	})
+	table := &ruleTable{
+		dataMatchers:     old.dataMatchers,
+		dataIndex:        old.dataIndex,
+		filenameMatchers: make([]FilenameMatcher, 0, len(old.filenameMatchers)+1),
+		treeMatchers:     old.treeMatchers,
+		xmlRootMatchers:  old.xmlRootMatchers,
+	}
+	table.filenameMatchers = append(table.filenameMatchers, old.filenameMatchers[:i]...)
+	table.filenameMatchers = append(table.filenameMatchers, m)
+	table.filenameMatchers = append(table.filenameMatchers, old.filenameMatchers[i:]...)
+	table.filenameIndex = newFilenameIndex(table.filenameMatchers)
+	d.rules.Store(table)
+	return nil
+}
+
+func validateDataMatcher(m DataMatcher) error {
+	if len(m.Submatches) == 0 {
+		return fmt.Errorf("%w: %s: no submatches", ErrInvalidRule, m.Result.MIME)
+	}
+	for _, sub := range m.Submatches {
+		if err := validateDataSubMatcher(sub); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidRule, m.Result.MIME, err)
+		}
+	}
+	return nil
+}
+
+func validateDataSubMatcher(m DataSubMatcher) error {
+	if len(m.Bytes) == 0 {
+		return fmt.Errorf("empty bytes")
+	}
+	if m.Offset < 0 && m.Offset+len(m.Bytes) > 0 {
+		return fmt.Errorf("bytes extend beyond the end of the content (offset %d, %d bytes)", m.Offset, len(m.Bytes))
+	}
+	if m.Range < 0 {
+		return fmt.Errorf("negative range %d", m.Range)
+	}
+	if len(m.Mask) > len(m.Bytes) {
+		return fmt.Errorf("mask is longer than bytes (%d > %d)", len(m.Mask), len(m.Bytes))
+	}
+	for _, child := range m.Children {
+		if err := validateDataSubMatcher(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateFilenameMatcher(m FilenameMatcher) error {
+	if m.Pattern == "" {
+		return fmt.Errorf("%w: %s: empty pattern", ErrInvalidRule, m.Result.MIME)
+	}
+	if _, err := doublestar.Match(m.Pattern, ""); err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidRule, m.Result.MIME, err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package magic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+var testRules = RuleSet{
+	DataMatchers: []DataMatcher{
+		{
+			Submatches: []DataSubMatcher{
+				{
+					Bytes:  []byte("TEST"),
+					Offset: 0,
+				},
+			},
+			Result: FileType{
+				Description: "Test file",
+				MIME:        "application/x-test",
+			},
+			Priority: 50,
+		},
+	},
+	FilenameMatchers: []FilenameMatcher{
+		{
+			Pattern: "*.test",
+			Result: FileType{
+				Description: "Test file",
+				MIME:        "application/x-test",
+			},
+			Priority: 50,
+		},
+	},
+}
+
+func TestDetectorUsesOwnRules(t *testing.T) {
+	d := NewDetector(testRules)
+
+	assert.Equal(t, d.Identify(bytes.NewBufferString("TEST")).MIME, "application/x-test")
+	assert.Equal(t, d.Identify(bytes.NewBufferString("\x89PNG\r\n\x1a\n")).MIME, "application/octet-stream")
+	assert.Equal(t, d.IdentifyWithFilename(bytes.NewBuffer(nil), "file.test").MIME, "application/x-test")
+	assert.Equal(t, d.IdentifyWithFilename(bytes.NewBuffer(nil), "file.png").MIME, "text/plain")
+
+	assert.Equal(t, Identify(bytes.NewBufferString("TEST")).MIME, "text/plain")
+	assert.Equal(t, Identify(bytes.NewBufferString("\x89PNG\r\n\x1a\n")).MIME, "image/png")
+}
+
+func TestDefaultRules(t *testing.T) {
+	rules := DefaultRules()
+	assert.Equal(t, len(rules.DataMatchers), len(dataMatchers)+len(extraDataMatchers))
+	assert.Equal(t, len(rules.FilenameMatchers), len(filenameMatchers)+len(extraFileMatchers))
+}
+
+func TestDetectorKeepsRuleOrder(t *testing.T) {
+	// rules of the same priority keep their order, so the first of several equally good matches always wins
+	var rules RuleSet
+	for i := range 100 {
+		rules.DataMatchers = append(rules.DataMatchers, DataMatcher{
+			Submatches: []DataSubMatcher{{Bytes: []byte("TEST")}},
+			Result:     FileType{MIME: fmt.Sprintf("application/x-test-%d", i)},
+			Priority:   50 + i%2,
+		})
+	}
+	d := NewDetector(rules)
+	assert.Equal(t, d.Identify(bytes.NewBufferString("TEST")).MIME, "application/x-test-1")
+
+	var expected, actual []string
+	for _, remainder := range []int{1, 0} {
+		for i := remainder; i < 100; i += 2 {
+			expected = append(expected, fmt.Sprintf("application/x-test-%d", i))
+		}
+	}
+	for _, m := range d.rules.Load().dataMatchers {
+		actual = append(actual, m.Result.MIME)
+	}
+	assert.DeepEqual(t, actual, expected)
+}
+
+func TestDetectorRegister(t *testing.T) {
+	d := NewDetector(testRules)
+
+	err := d.Register(DataMatcher{
+		Submatches: []DataSubMatcher{
+			{
+				Bytes:  []byte("TEST"),
+				Offset: 0,
+			},
+		},
+		Result: FileType{
+			MIME: "application/x-test-lower",
+		},
+		Priority: 40,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, d.Identify(bytes.NewBufferString("TEST")).MIME, "application/x-test")
+
+	err = d.Register(DataMatcher{
+		Submatches: []DataSubMatcher{
+			{
+				Bytes:  []byte("TEST"),
+				Offset: 0,
+			},
+		},
+		Result: FileType{
+			MIME: "application/x-test-higher",
+		},
+		Priority: 60,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, d.Identify(bytes.NewBufferString("TEST")).MIME, "application/x-test-higher")
+
+	err = d.RegisterFilename(FilenameMatcher{
+		Pattern: "*.other",
+		Result: FileType{
+			MIME: "application/x-other",
+		},
+		Priority: 50,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, d.IdentifyWithFilename(bytes.NewBuffer(nil), "file.other").MIME, "application/x-other")
+
+	// the default detector is unaffected
+	assert.Equal(t, Identify(bytes.NewBufferString("TEST")).MIME, "text/plain")
+}
+
+func TestDetectorRegisterInvalid(t *testing.T) {
+	d := NewDetector(testRules)
+
+	tests := []struct {
+		name    string
+		matcher DataMatcher
+	}{
+		{
+			name:    "no submatches",
+			matcher: DataMatcher{},
+		},
+		{
+			name: "empty bytes",
+			matcher: DataMatcher{
+				Submatches: []DataSubMatcher{{Offset: 0}},
+			},
+		},
+		{
+			name: "beyond end of content",
+			matcher: DataMatcher{
+				Submatches: []DataSubMatcher{{Bytes: []byte("TEST"), Offset: -2}},
+			},
+		},
+		{
+			name: "negative range",
+			matcher: DataMatcher{
+				Submatches: []DataSubMatcher{{Bytes: []byte("TEST"), Range: -1}},
+			},
+		},
+		{
+			name: "mask longer than bytes",
+			matcher: DataMatcher{
+				Submatches: []DataSubMatcher{{Bytes: []byte("A"), Offset: 0, Mask: []byte{0xff, 0xff}}},
+			},
+		},
+		{
+			name: "invalid child",
+			matcher: DataMatcher{
+				Submatches: []DataSubMatcher{
+					{
+						Bytes:    []byte("A"),
+						Offset:   0,
+						Children: []DataSubMatcher{{Offset: 1}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Assert(t, errors.Is(d.Register(test.matcher), ErrInvalidRule))
+		})
+	}
+
+	err := d.RegisterFilename(FilenameMatcher{Pattern: "[unclosed"})
+	assert.Assert(t, errors.Is(err, ErrInvalidRule))
+}
+
+func TestDetectorRegisterConcurrently(t *testing.T) {
+	d := NewDetector(testRules)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = d.Register(DataMatcher{
+				Submatches: []DataSubMatcher{{Bytes: []byte{byte(i)}, Offset: 8}},
+				Result:     FileType{MIME: "application/x-concurrent"},
+				Priority:   i * 10,
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, d.Identify(bytes.NewBufferString("TEST")).MIME, "application/x-test")
+		}()
+	}
+	wg.Wait()
+
+	rules := d.rules.Load()
+	assert.Equal(t, len(rules.dataMatchers), 9)
+	for i := 1; i < len(rules.dataMatchers); i++ {
+		assert.Assert(t, rules.dataMatchers[i-1].Priority >= rules.dataMatchers[i].Priority)
+	}
+}
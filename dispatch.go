@@ -0,0 +1,186 @@
+package magic
+
+import (
+	"math/bits"
+	"slices"
+	"sync"
+)
+
+// dataIndex speeds up content matching by avoiding evaluating most rules. Rules which only compare bytes at fixed
+// offsets are indexed by the first byte they expect at each of those offsets, so only the rules expecting the bytes
+// actually found in the data are evaluated. Every other rule, such as one searching a range of offsets or masking
+// its first byte, is always evaluated. Content is only read as far as the rules which are evaluated look, so rules
+// with offsets beyond what has been read are evaluated too, reading further as they need.
+type dataIndex struct {
+	matchers []DataMatcher
+	// offsets are the distinct offsets of the indexed rules, in ascending order
+	offsets []int
+	// starts holds, for each of the offsets and each byte value, the range of entries listing the rules which
+	// expect that byte at that offset
+	starts  [][257]int32
+	entries []int32
+	// always is a bitset of the rules which could not be indexed
+	always []uint64
+	// pending holds, for each of the offsets, a bitset of the rules indexed at that offset or a later one
+	pending [][]uint64
+	// tails is a bitset of the rules which look at the end of the content
+	tails []uint64
+	// extent is the number of bytes needed from the start of the content to evaluate every rule
+	extent int
+	// tailExtent is the number of bytes needed from the end of the content to evaluate every rule
+	tailExtent int
+	// scratch holds bitsets of candidate rules, so that matching does not allocate
+	scratch sync.Pool
+}
+
+type dataIndexKey struct {
+	offset int
+	b      byte
+}
+
+func newDataIndex(matchers []DataMatcher) *dataIndex {
+	words := (len(matchers) + 63) / 64
+	index := &dataIndex{
+		matchers: matchers,
+		always:   make([]uint64, words),
+		tails:    make([]uint64, words),
+	}
+	index.scratch.New = func() any {
+		candidates := make([]uint64, words)
+		return &candidates
+	}
+
+	keyed := make(map[dataIndexKey][]int32)
+	for i, m := range matchers {
+		for _, sub := range m.Submatches {
+			head, tail := sub.extent()
+			index.extent, index.tailExtent = max(index.extent, head), max(index.tailExtent, tail)
+			if tail > 0 {
+				index.tails[i/64] |= 1 << (i % 64)
+			}
+		}
+		keys, ok := indexKeys(m)
+		if !ok {
+			index.always[i/64] |= 1 << (i % 64)
+			continue
+		}
+		for _, key := range keys {
+			if entries := keyed[key]; len(entries) == 0 || entries[len(entries)-1] != int32(i) {
+				keyed[key] = append(entries, int32(i))
+			}
+		}
+	}
+
+	for key := range keyed {
+		if !slices.Contains(index.offsets, key.offset) {
+			index.offsets = append(index.offsets, key.offset)
+		}
+	}
+	slices.Sort(index.offsets)
+	index.starts = make([][257]int32, len(index.offsets))
+	for i, offset := range index.offsets {
+		for b := range 256 {
+			index.starts[i][b] = int32(len(index.entries))
+			index.entries = append(index.entries, keyed[dataIndexKey{offset: offset, b: byte(b)}]...)
+		}
+		index.starts[i][256] = int32(len(index.entries))
+	}
+	index.pending = make([][]uint64, len(index.offsets))
+	for i := len(index.offsets) - 1; i >= 0; i-- {
+		index.pending[i] = make([]uint64, words)
+		if i+1 < len(index.offsets) {
+			copy(index.pending[i], index.pending[i+1])
+		}
+		for _, m := range index.entries[index.starts[i][0]:index.starts[i][256]] {
+			index.pending[i][m/64] |= 1 << (m % 64)
+		}
+	}
+	return index
+}
+
+// indexKeys returns the offset and first byte expected by each of the submatches of the provided rule. It returns
+// false if any submatch cannot be indexed, such as one with no bytes, in which case the rule must always be
+// evaluated.
+func indexKeys(m DataMatcher) ([]dataIndexKey, bool) {
+	keys := make([]dataIndexKey, 0, len(m.Submatches))
+	for _, sub := range m.Submatches {
+		if len(sub.Bytes) == 0 || sub.Offset < 0 || sub.Range > 0 || (len(sub.Mask) > 0 && sub.Mask[0] != 0xff) {
+			return nil, false
+		}
+		keys = append(keys, dataIndexKey{offset: sub.Offset, b: sub.Bytes[0]})
+	}
+	return keys, true
+}
+
+// match calls fn with each rule which matches the content of b, in order, until fn returns false. If tied is set,
+// only the rules sharing the priority of the first to match are evaluated, as the rest could not identify the
+// content. The content is only read as far as the rules which are evaluated look.
+func (index *dataIndex) match(b *bufferedReader, tied bool, fn func(m *DataMatcher) bool) {
+	scratch := index.scratch.Get().(*[]uint64)
+	defer index.scratch.Put(scratch)
+
+	candidates := *scratch
+	c := b.available()
+	index.collect(&c, candidates)
+	var matched *DataMatcher
+	for w, word := range candidates {
+		for word != 0 {
+			i := w*64 + bits.TrailingZeros64(word)
+			word &= word - 1
+			m := &index.matchers[i]
+			if tied && matched != nil && m.Priority < matched.Priority {
+				return
+			}
+			if index.tails[w]&(1<<(i%64)) != 0 && b.available().tail == nil {
+				if b.readTail(); b.tail == nil && matched == nil {
+					// the end of the content is only known once everything has been read, so if nothing has matched,
+					// as much is read as the rules could need in the hope of reaching it
+					b.readAhead(index.extent)
+				}
+			}
+			if !m.matchBuffered(b) {
+				continue
+			}
+			if matched == nil {
+				matched = m
+			}
+			if !fn(m) {
+				return
+			}
+		}
+	}
+}
+
+// evaluate calls fn with every rule which the index could not rule out, in order, and whether it matches the
+// content. Unlike match, it allocates, so it is only used to explain results.
+func (index *dataIndex) evaluate(c *content, fn func(m *DataMatcher, matched bool)) {
+	candidates := make([]uint64, len(index.always))
+	index.collect(c, candidates)
+	for w, word := range candidates {
+		for word != 0 {
+			m := &index.matchers[w*64+bits.TrailingZeros64(word)]
+			word &= word - 1
+			fn(m, m.matchContent(c))
+		}
+	}
+}
+
+// collect sets the bits of candidates for the rules which could match the content. Rules indexed at offsets which
+// have not been read yet could match whatever is there.
+func (index *dataIndex) collect(c *content, candidates []uint64) {
+	copy(candidates, index.always)
+	data := c.head
+	for i, offset := range index.offsets {
+		if offset >= len(data) {
+			for w, word := range index.pending[i] {
+				candidates[w] |= word
+			}
+			break
+		}
+		row := &index.starts[i]
+		b := int(data[offset])
+		for _, m := range index.entries[row[b]:row[b+1]] {
+			candidates[m/64] |= 1 << (m % 64)
+		}
+	}
+}
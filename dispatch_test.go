@@ -0,0 +1,184 @@
+package magic
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// sampleFor returns content which satisfies the first submatch of the provided rule.
+func sampleFor(m DataMatcher) []byte {
+	sub := m.Submatches[0]
+	head, tail := sub.extent()
+	data := make([]byte, max(head, tail))
+	for {
+		offset := sub.Offset + sub.Range
+		if sub.Offset < 0 {
+			offset = len(data) + sub.Offset
+		}
+		copy(data[offset:], sub.Bytes)
+		if len(sub.Children) == 0 {
+			return data
+		}
+		sub = sub.Children[0]
+	}
+}
+
+func TestDataIndexMatchesLinearScan(t *testing.T) {
+	rules := defaultDetector.rules.Load()
+	for _, m := range rules.dataMatchers {
+		c := content{head: sampleFor(m)}
+		c.tail = c.head
+
+		var expected []string
+		for _, candidate := range rules.dataMatchers {
+			if candidate.matchContent(&c) {
+				expected = append(expected, candidate.Result.MIME)
+			}
+		}
+		var actual []string
+		b := defaultDetector.bufferBytes(c.head)
+		rules.dataIndex.match(&b, false, func(m *DataMatcher) bool {
+			actual = append(actual, m.Result.MIME)
+			return true
+		})
+
+		assert.DeepEqual(t, actual, expected)
+	}
+}
+
+func TestDataSubMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		matcher  DataSubMatcher
+		data     string
+		expected bool
+	}{
+		{
+			name:     "exact offset",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: 2},
+			data:     "xxAB",
+			expected: true,
+		},
+		{
+			name:     "truncated",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: 2},
+			data:     "xxA",
+			expected: false,
+		},
+		{
+			name:     "range",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: 1, Range: 2},
+			data:     "xxxABx",
+			expected: true,
+		},
+		{
+			name:     "beyond range",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: 1, Range: 2},
+			data:     "xxxxAB",
+			expected: false,
+		},
+		{
+			name:     "range truncated by data",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: 0, Range: 3},
+			data:     "xAB",
+			expected: true,
+		},
+		{
+			name:     "mask",
+			matcher:  DataSubMatcher{Bytes: []byte{0x40, 'B'}, Offset: 0, Mask: []byte{0xf0}},
+			data:     "AB",
+			expected: true,
+		},
+		{
+			name:     "masked mismatch",
+			matcher:  DataSubMatcher{Bytes: []byte{0x40, 'B'}, Offset: 0, Mask: []byte{0xf0}},
+			data:     "AC",
+			expected: false,
+		},
+		{
+			name:     "masked range",
+			matcher:  DataSubMatcher{Bytes: []byte{0x40, 'B'}, Offset: 0, Range: 2, Mask: []byte{0xf0}},
+			data:     "xxAB",
+			expected: true,
+		},
+		{
+			name:     "end of content",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: -3},
+			data:     "xxABx",
+			expected: true,
+		},
+		{
+			name:     "before start of content",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: -6},
+			data:     "ABxxx",
+			expected: false,
+		},
+		{
+			name:     "range from end of content",
+			matcher:  DataSubMatcher{Bytes: []byte("AB"), Offset: -5, Range: 3},
+			data:     "xxxxABx",
+			expected: true,
+		},
+		{
+			name: "children",
+			matcher: DataSubMatcher{Bytes: []byte("A"), Offset: 0, Children: []DataSubMatcher{
+				{Bytes: []byte("C"), Offset: 1},
+				{Bytes: []byte("B"), Offset: 1},
+			}},
+			data:     "AB",
+			expected: true,
+		},
+		{
+			name: "children mismatch",
+			matcher: DataSubMatcher{Bytes: []byte("A"), Offset: 0, Children: []DataSubMatcher{
+				{Bytes: []byte("C"), Offset: 1},
+			}},
+			data:     "AB",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.matcher.Match([]byte(test.data)), test.expected)
+		})
+	}
+}
+
+func TestDataIndexAcceptsEmptyBytes(t *testing.T) {
+	d := NewDetector(RuleSet{DataMatchers: []DataMatcher{{
+		Submatches: []DataSubMatcher{{Offset: 0}},
+		Result:     FileType{MIME: "application/x-anything"},
+	}}})
+	rules := d.rules.Load()
+	assert.Equal(t, len(rules.dataIndex.always), 1)
+	assert.Equal(t, rules.dataIndex.always[0], uint64(1))
+	// no bytes are compared, so the rule matches any content
+	assert.Equal(t, d.IdentifyBytes([]byte("hello")).MIME, "application/x-anything")
+}
+
+func TestDataIndexDoesNotAllocate(t *testing.T) {
+	rules := defaultDetector.rules.Load()
+	for _, data := range benchmarkSamples {
+		b := defaultDetector.bufferBytes(data)
+		allocs := testing.AllocsPerRun(100, func() {
+			rules.dataIndex.match(&b, false, func(*DataMatcher) bool {
+				return false
+			})
+		})
+		assert.Equal(t, allocs, float64(0))
+	}
+}
+
+func BenchmarkMatchData(b *testing.B) {
+	rules := defaultDetector.rules.Load()
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		data := benchmarkSamples[i%len(benchmarkSamples)]
+		b := defaultDetector.bufferBytes(data)
+		rules.dataIndex.match(&b, false, func(*DataMatcher) bool {
+			return false
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package magic
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// RuleOrigin describes where a rule came from.
+type RuleOrigin int
+
+const (
+	// RuleOriginCustom indicates the rule was provided by the caller, either to NewDetector or by registering it.
+	RuleOriginCustom RuleOrigin = iota
+	// RuleOriginFreedesktop indicates the rule comes from the freedesktop shared-mime-info database.
+	RuleOriginFreedesktop
+	// RuleOriginExtra indicates the rule is one of the extra rules this package adds to the freedesktop database.
+	RuleOriginExtra
+)
+
+func (o RuleOrigin) String() string {
+	switch o {
+	case RuleOriginCustom:
+		return "custom"
+	case RuleOriginFreedesktop:
+		return "freedesktop"
+	case RuleOriginExtra:
+		return "extra"
+	default:
+		return "unknown"
+	}
+}
+
+// Explanation describes how a file type was identified, to help diagnose unexpected results.
+type Explanation struct {
+	FileType FileType
+	// Winner is the rule which determined FileType, or nil if no rule did, in which case the content was identified
+	// as generic text or binary data.
+	Winner *RuleTrace
+	// Reason explains how the filename and the content were weighed against each other. It is empty if only the
+	// content was used.
+	Reason string
+	// Rejected lists the other rules which matched, in full or in part, along with why each was not chosen. Rules
+	// which do not match at all are not listed.
+	Rejected []RuleTrace
+}
+
+// RuleTrace describes a rule which was evaluated while identifying a file type.
+type RuleTrace struct {
+	Source   MatchSource
+	Origin   RuleOrigin
+	Result   FileType
+	Priority int
+	// Pattern is the filename pattern of a glob rule, or the root element of an XML rule in the form
+	// "{namespace}name".
+	Pattern string
+	// Path is the chain of submatches of a magic rule which matched, from one of its submatches down through the
+	// Children of each. If the rule only matched in part, it ends with the last submatch which matched.
+	Path []SubmatchTrace
+	// Reason explains why the rule was rejected. It is empty for the winning rule.
+	Reason string
+}
+
+// SubmatchTrace describes where a submatch of a magic rule matched.
+type SubmatchTrace struct {
+	// Offset is the position at which Bytes were found, from the start of the content or, if it is negative, from
+	// the end.
+	Offset int
+	Bytes  []byte
+	Mask   []byte
+}
+
+func (t RuleTrace) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s rule for %s (%s, priority %d)", t.Source, t.Result.MIME, t.Origin, t.Priority)
+	if t.Pattern != "" {
+		fmt.Fprintf(&sb, " %q", t.Pattern)
+	}
+	for i, step := range t.Path {
+		if i == 0 {
+			sb.WriteString(" matched ")
+		} else {
+			sb.WriteString(" then ")
+		}
+		fmt.Fprintf(&sb, "%s at %d", hex.EncodeToString(step.Bytes), step.Offset)
+		if len(step.Mask) > 0 {
+			fmt.Fprintf(&sb, " with mask %s", hex.EncodeToString(step.Mask))
+		}
+	}
+	if t.Reason != "" {
+		fmt.Fprintf(&sb, ": %s", t.Reason)
+	}
+	return sb.String()
+}
+
+func (e Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "identified as %s", e.FileType.MIME)
+	if e.Winner != nil {
+		fmt.Fprintf(&sb, " by %s", e.Winner)
+	} else {
+		sb.WriteString(" as no rule matched")
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(&sb, "; %s", e.Reason)
+	}
+	for _, t := range e.Rejected {
+		fmt.Fprintf(&sb, "\n  rejected %s", t)
+	}
+	return sb.String()
+}
+
+// Explain is like Identify, but also explains the result. See Detector.Explain.
+func Explain(r io.Reader) Explanation {
+	return defaultDetector.Explain(r)
+}
+
+// Explain is like Identify, but also reports the rule which determined the result and the rules which were
+// rejected.
+func (d *Detector) Explain(r io.Reader) Explanation {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	var e Explanation
+	e.FileType = d.rules.Load().identify(b, &e)
+	return e
+}
+
+// ExplainWithFilename is like IdentifyWithFilename, but also explains the result. See Detector.ExplainWithFilename.
+func ExplainWithFilename(r io.Reader, filename string) Explanation {
+	return defaultDetector.ExplainWithFilename(r, filename)
+}
+
+// ExplainWithFilename is like IdentifyWithFilename, but also reports the rule which determined the result and the
+// rules which were rejected.
+func (d *Detector) ExplainWithFilename(r io.Reader, filename string) Explanation {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	var e Explanation
+	e.FileType = d.identifyWithFilename(d.rules.Load(), b, filename, &e)
+	return e
+}
+
+// The methods below record the decisions made while identifying a file type. They do nothing when called on a nil
+// Explanation, so that identification only pays for them when an explanation was asked for.
+
+// decide records how the filename and the content were weighed against each other.
+func (e *Explanation) decide(reason string) {
+	if e != nil {
+		e.Reason = reason
+	}
+}
+
+// accept makes t the winning rule, rejecting the previous winner, if any, for the provided reason.
+func (e *Explanation) accept(t RuleTrace, reason string) {
+	if e.Winner != nil {
+		e.reject(*e.Winner, reason)
+	}
+	e.Winner = &t
+}
+
+func (e *Explanation) reject(t RuleTrace, reason string) {
+	t.Reason = reason
+	e.Rejected = append(e.Rejected, t)
+}
+
+// traceData records the evaluation of the content rules, of which winner was the first to match.
+func (e *Explanation) traceData(index *dataIndex, c *content, winner *DataMatcher) {
+	if e == nil {
+		return
+	}
+	index.evaluate(c, func(m *DataMatcher, matched bool) {
+		t := dataTrace(m, c)
+		switch {
+		case m == winner:
+			e.accept(t, "")
+		case !matched:
+			if len(t.Path) > 0 {
+				e.reject(t, "none of the children of the last submatch match the content")
+			}
+		case m.Priority < winner.Priority:
+			e.reject(t, fmt.Sprintf("priority %d is lower than %d", m.Priority, winner.Priority))
+		case winner.matchLength(c) > m.matchLength(c):
+			e.reject(t, "a rule of the same priority matches more of the content")
+		case IsA(winner.Result.MIME, m.Result.MIME) && !IsA(m.Result.MIME, winner.Result.MIME):
+			e.reject(t, fmt.Sprintf("a rule of the same priority matches %s, which is a sub-class", winner.Result.MIME))
+		default:
+			e.reject(t, "a rule of the same priority which comes first also matches")
+		}
+	})
+}
+
+// traceXMLRoot records that m identified the content by its root element, refining the result of the content rules.
+func (e *Explanation) traceXMLRoot(m *XMLRootMatcher) {
+	if e == nil {
+		return
+	}
+	e.accept(RuleTrace{
+		Source:  MatchSourceXMLRoot,
+		Origin:  m.Origin,
+		Result:  m.Result,
+		Pattern: fmt.Sprintf("{%s}%s", m.NamespaceURI, m.LocalName),
+	}, "the root element of the XML document identifies a more specific type")
+}
+
+// traceFilenames records the filename rules which match the filename but are not among the best matches, hits.
+func (e *Explanation) traceFilenames(rules *ruleTable, filename string, hits []int) {
+	if e == nil || len(hits) == 0 {
+		return
+	}
+	filename = filepath.Base(filename)
+	lower := strings.ToLower(filename)
+	best := &rules.filenameMatchers[hits[0]]
+	literal := classifyGlob(best.Pattern) == globLiteral
+	for i := range rules.filenameMatchers {
+		m := &rules.filenameMatchers[i]
+		if !m.match(filename, lower) || slices.Contains(hits, i) {
+			continue
+		}
+		t := globTrace(m)
+		switch {
+		case literal && classifyGlob(m.Pattern) != globLiteral:
+			e.reject(t, fmt.Sprintf("the literal filename %q also matched", best.Pattern))
+		case m.Priority < best.Priority:
+			e.reject(t, fmt.Sprintf("weight %d is lower than %d", m.Priority, best.Priority))
+		default:
+			e.reject(t, fmt.Sprintf("the longer pattern %q also matched", best.Pattern))
+		}
+	}
+}
+
+// acceptGlobs records that the nth of the best filename matches, hits, determined the result. The others are rejected
+// for the provided reason, unless they are for the same type.
+func (e *Explanation) acceptGlobs(rules *ruleTable, hits []int, n int, reason string) {
+	if e == nil {
+		return
+	}
+	winner := &rules.filenameMatchers[hits[n]]
+	e.accept(globTrace(winner), "the filename was used instead")
+	for _, i := range hits {
+		m := &rules.filenameMatchers[i]
+		switch {
+		case m == winner:
+		case m.Result.MIME == winner.Result.MIME:
+			e.reject(globTrace(m), "a pattern for the same type which is evaluated first also matches")
+		default:
+			e.reject(globTrace(m), reason)
+		}
+	}
+}
+
+// rejectGlobs records that none of the best filename matches, hits, determined the result, for the provided reason.
+func (e *Explanation) rejectGlobs(rules *ruleTable, hits []int, reason string) {
+	if e == nil {
+		return
+	}
+	for _, i := range hits {
+		e.reject(globTrace(&rules.filenameMatchers[i]), reason)
+	}
+}
+
+func dataTrace(m *DataMatcher, c *content) RuleTrace {
+	t := RuleTrace{
+		Source:   MatchSourceMagic,
+		Origin:   m.Origin,
+		Result:   m.Result,
+		Priority: m.Priority,
+	}
+	for i := range m.Submatches {
+		path, ok := m.Submatches[i].trace(c)
+		if len(path) > len(t.Path) {
+			t.Path = path
+		}
+		if ok {
+			t.Path = path
+			break
+		}
+	}
+	return t
+}
+
+func globTrace(m *FilenameMatcher) RuleTrace {
+	return RuleTrace{
+		Source:   MatchSourceGlob,
+		Origin:   m.Origin,
+		Result:   m.Result,
+		Priority: m.Priority,
+		Pattern:  m.Pattern,
+	}
+}
+
+// trace returns the chain of submatches which matched the content, starting with this one, and whether the chain
+// ends with a submatch which has no children. If the submatch does not match in full, the longest chain which
+// matched in part is returned.
+func (m *DataSubMatcher) trace(c *content) ([]SubmatchTrace, bool) {
+	offset, ok := m.find(c)
+	if !ok {
+		return nil, false
+	}
+	path := []SubmatchTrace{{Offset: offset, Bytes: m.Bytes, Mask: m.Mask}}
+	if len(m.Children) == 0 {
+		return path, true
+	}
+	var partial []SubmatchTrace
+	for i := range m.Children {
+		childPath, ok := m.Children[i].trace(c)
+		if ok {
+			return append(path, childPath...), true
+		}
+		if len(childPath) > len(partial) {
+			partial = childPath
+		}
+	}
+	return append(path, partial...), false
+}
+
+// find is like matchAny, but also returns the offset at which the bytes were first found, which is relative to the
+// end of the content if the submatch is.
+func (m *DataSubMatcher) find(c *content) (int, bool) {
+	data, start := c.locate(m.Offset)
+	if start < 0 || len(data) < start+len(m.Bytes) {
+		return 0, false
+	}
+	end := min(start+m.Range+len(m.Bytes), len(data))
+	for offset := start; offset+len(m.Bytes) <= end; offset++ {
+		if !m.matchAt(data, offset) {
+			continue
+		}
+		if m.Offset < 0 {
+			return offset - len(data), true
+		}
+		return offset, true
+	}
+	return 0, false
+}
@@ -0,0 +1,120 @@
+package magic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestExplain(t *testing.T) {
+	e := Explain(bytes.NewReader([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")))
+	assert.Equal(t, e.FileType.MIME, "image/png")
+	assert.Equal(t, e.Winner.Source, MatchSourceMagic)
+	assert.Equal(t, e.Winner.Origin, RuleOriginFreedesktop)
+	assert.Equal(t, e.Winner.Result.MIME, "image/png")
+	assert.DeepEqual(t, e.Winner.Path, []SubmatchTrace{{Offset: 0, Bytes: []byte("\x89PNG\r\n\x1a\n")}})
+	assert.Equal(t, e.Winner.Reason, "")
+
+	// APNG starts the same way, but has further submatches which do not match
+	var apng *RuleTrace
+	for i, r := range e.Rejected {
+		if r.Result.MIME == "image/apng" {
+			apng = &e.Rejected[i]
+		}
+	}
+	assert.Assert(t, apng != nil)
+	assert.Equal(t, apng.Path[0].Offset, 0)
+	assert.Assert(t, apng.Reason != "")
+}
+
+func TestExplainMatchesIdentify(t *testing.T) {
+	for _, data := range benchmarkSamples {
+		e := Explain(bytes.NewReader(data))
+		assert.Equal(t, e.FileType, Identify(bytes.NewReader(data)))
+		if e.Winner != nil {
+			assert.Equal(t, e.Winner.Result, e.FileType)
+		}
+		for _, filename := range benchmarkFilenames {
+			e := ExplainWithFilename(bytes.NewReader(data), filename)
+			assert.Equal(t, e.FileType, IdentifyWithFilename(bytes.NewReader(data), filename))
+		}
+	}
+}
+
+func TestExplainOrigin(t *testing.T) {
+	e := Explain(bytes.NewReader([]byte("\xcf\xfa\xed\xfe")))
+	assert.Equal(t, e.Winner.Origin, RuleOriginExtra)
+
+	d := NewDetector(RuleSet{
+		DataMatchers: []DataMatcher{
+			{
+				Submatches: []DataSubMatcher{
+					{
+						Bytes: []byte("tHE"),
+						Mask:  []byte{0xff, 0xdf, 0xdf},
+					},
+				},
+				Result:   FileType{MIME: "application/x-end"},
+				Priority: 50,
+			},
+		},
+	})
+	e = d.Explain(bytes.NewReader([]byte("the end")))
+	assert.Equal(t, e.FileType.MIME, "application/x-end")
+	assert.Equal(t, e.Winner.Origin, RuleOriginCustom)
+	assert.DeepEqual(t, e.Winner.Path, []SubmatchTrace{{Offset: 0, Bytes: []byte("tHE"), Mask: []byte{0xff, 0xdf, 0xdf}}})
+}
+
+func TestExplainEndOfContent(t *testing.T) {
+	vhd := append(bytes.Repeat([]byte{0xff}, 512), "conectix"...)
+	vhd = append(vhd, make([]byte, 504)...)
+	e := Explain(bytes.NewReader(vhd))
+	assert.Equal(t, e.FileType.MIME, "application/x-vhd-disk")
+	assert.Equal(t, e.Winner.Origin, RuleOriginExtra)
+	assert.DeepEqual(t, e.Winner.Path, []SubmatchTrace{{Offset: -512, Bytes: []byte("conectix")}})
+}
+
+func TestExplainXMLRoot(t *testing.T) {
+	e := Explain(bytes.NewReader([]byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`)))
+	assert.Equal(t, e.FileType.MIME, "image/svg+xml")
+	assert.Equal(t, e.Winner.Source, MatchSourceXMLRoot)
+	assert.Equal(t, e.Winner.Pattern, "{http://www.w3.org/2000/svg}svg")
+
+	var refined bool
+	for _, r := range e.Rejected {
+		if r.Source == MatchSourceMagic && r.Result.MIME == "image/svg+xml" {
+			refined = strings.Contains(r.Reason, "root element")
+		}
+	}
+	assert.Assert(t, refined)
+}
+
+func TestExplainNoMatch(t *testing.T) {
+	e := Explain(bytes.NewReader([]byte("\x99\x99\x99\x99")))
+	assert.Equal(t, e.FileType.MIME, "application/octet-stream")
+	assert.Assert(t, e.Winner == nil)
+	assert.Equal(t, e.String(), "identified as application/octet-stream as no rule matched")
+}
+
+func TestExplainWithFilename(t *testing.T) {
+	e := ExplainWithFilename(bytes.NewReader(nil), "archive.tar.gz")
+	assert.Equal(t, e.Winner.Source, MatchSourceGlob)
+	assert.Equal(t, e.Winner.Pattern, "*.tar.gz")
+	assert.Equal(t, len(e.Rejected), 1)
+	assert.Equal(t, e.Rejected[0].Pattern, "*.gz")
+	assert.Equal(t, e.Rejected[0].Reason, `the longer pattern "*.tar.gz" also matched`)
+
+	// several types match the name, so the content decides
+	e = ExplainWithFilename(bytes.NewReader([]byte("PK\x03\x04")), "Makefile")
+	assert.Equal(t, e.FileType.MIME, "application/zip")
+	assert.Equal(t, e.Winner.Source, MatchSourceMagic)
+	var globs int
+	for _, r := range e.Rejected {
+		if r.Source == MatchSourceGlob {
+			globs++
+		}
+	}
+	assert.Equal(t, globs, 2)
+}
@@ -1,19 +1,5 @@
 package magic
 
-import "sort"
-
-var allDataMatchers = append(dataMatchers, extraDataMatchers...)
-var allFilenameMatchers = append(filenameMatchers, extraFileMatchers...)
-
-func init() {
-	sort.Slice(allDataMatchers, func(i, j int) bool {
-		return allDataMatchers[i].Priority > allDataMatchers[j].Priority
-	})
-	sort.Slice(allFilenameMatchers, func(i, j int) bool {
-		return allFilenameMatchers[i].Priority > allFilenameMatchers[j].Priority
-	})
-}
-
 var extraFileMatchers = []FilenameMatcher{
 	{
 		Pattern: "go.mod",
@@ -51,8 +37,8 @@ var extraDataMatchers = []DataMatcher{
 	{
 		Submatches: []DataSubMatcher{
 			{
-				Bytes:   []byte("\xcf\xfa\xed\xfe"), // 64-bit little-endian
-				Offsets: []int{0},
+				Bytes:  []byte("\xcf\xfa\xed\xfe"), // 64-bit little-endian
+				Offset: 0,
 			},
 		},
 		Result: FileType{
@@ -65,8 +51,8 @@ var extraDataMatchers = []DataMatcher{
 	{
 		Submatches: []DataSubMatcher{
 			{
-				Bytes:   []byte("\xfe\xed\xfa\xcf"),
-				Offsets: []int{0},
+				Bytes:  []byte("\xfe\xed\xfa\xcf"),
+				Offset: 0,
 			},
 		},
 		Result: FileType{
@@ -79,8 +65,8 @@ var extraDataMatchers = []DataMatcher{
 	{
 		Submatches: []DataSubMatcher{
 			{
-				Bytes:   []byte("\xce\xfa\xed\xfe"), // 32-bit little-endian
-				Offsets: []int{0},
+				Bytes:  []byte("\xce\xfa\xed\xfe"), // 32-bit little-endian
+				Offset: 0,
 			},
 		},
 		Result: FileType{
@@ -93,8 +79,8 @@ var extraDataMatchers = []DataMatcher{
 	{
 		Submatches: []DataSubMatcher{
 			{
-				Bytes:   []byte("\xfe\xed\xfa\xce"), // 32-bit big-endian
-				Offsets: []int{0},
+				Bytes:  []byte("\xfe\xed\xfa\xce"), // 32-bit big-endian
+				Offset: 0,
 			},
 		},
 		Result: FileType{
@@ -104,4 +90,51 @@ var extraDataMatchers = []DataMatcher{
 		},
 		Priority: 50,
 	},
+	{
+		Submatches: []DataSubMatcher{
+			{
+				Bytes:  []byte("conectix"), // footer of a fixed size VHD, which has no header
+				Offset: -512,
+			},
+		},
+		Result: FileType{
+			Description:          "VHD disk image",
+			RecommendedExtension: ".vhd",
+			Icon:                 "application-x-generic",
+			MIME:                 "application/x-vhd-disk",
+			Acronym:              "VHD",
+			ExpandedAcronym:      "Virtual Hard Disk",
+		},
+		Priority: 50,
+	},
+	{
+		Submatches: []DataSubMatcher{
+			{
+				Bytes:  []byte("PK\x05\x06"), // end of central directory, with no archive comment
+				Offset: -22,
+			},
+		},
+		Result: FileType{
+			Description:          "Zip archive",
+			RecommendedExtension: ".zip",
+			Icon:                 "package-x-generic",
+			MIME:                 "application/zip",
+		},
+		Priority: 30,
+	},
+	{
+		Submatches: []DataSubMatcher{
+			{
+				Bytes:  []byte("TAG"), // ID3v1 tag, for MP3s without an ID3v2 tag
+				Offset: -128,
+			},
+		},
+		Result: FileType{
+			Description:          "MP3 audio",
+			RecommendedExtension: ".mp3",
+			Icon:                 "application-x-generic",
+			MIME:                 "audio/mpeg",
+		},
+		Priority: 20,
+	},
 }
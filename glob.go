@@ -0,0 +1,270 @@
+package magic
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// globKind classifies filename patterns, in the order the freedesktop specification says they are evaluated.
+type globKind int
+
+const (
+	// globLiteral patterns contain no wildcards, e.g. "Makefile".
+	globLiteral globKind = iota
+	// globSuffix patterns are a single leading "*" followed by a literal suffix, e.g. "*.tar.gz".
+	globSuffix
+	// globFull patterns are anything else, e.g. "README*" or "*.[ch]".
+	globFull
+)
+
+func classifyGlob(pattern string) globKind {
+	if !strings.ContainsAny(pattern, "*?[\\{") {
+		return globLiteral
+	}
+	if strings.HasPrefix(pattern, "*") && !strings.ContainsAny(pattern[1:], "*?[\\{") {
+		return globSuffix
+	}
+	return globFull
+}
+
+// match reports whether the matcher matches the provided filename. lower must be the filename converted to
+// lower-case, and is used unless the matcher is case-sensitive.
+func (m *FilenameMatcher) match(filename, lower string) bool {
+	switch classifyGlob(m.Pattern) {
+	case globLiteral:
+		if m.CaseSensitive {
+			return filename == m.Pattern
+		}
+		return strings.EqualFold(filename, m.Pattern)
+	case globSuffix:
+		suffix := m.Pattern[1:]
+		if m.CaseSensitive {
+			return strings.HasSuffix(filename, suffix)
+		}
+		return len(filename) >= len(suffix) && strings.EqualFold(filename[len(filename)-len(suffix):], suffix)
+	default:
+		if m.CaseSensitive {
+			ok, _ := doublestar.Match(m.Pattern, filename)
+			return ok
+		}
+		ok, _ := doublestar.Match(strings.ToLower(m.Pattern), lower)
+		return ok
+	}
+}
+
+// filenameIndex speeds up filename matching by avoiding evaluating most patterns. Literal and suffix patterns are
+// looked up by their lower-case text, leaving only the full glob patterns to be evaluated one by one.
+type filenameIndex struct {
+	literals map[string][]int // lower-case pattern -> matcher indices
+	suffixes map[string][]int // lower-case suffix without the leading "*" -> matcher indices
+	// maxSuffix is the length of the longest suffix, which limits the suffixes of a filename to look up
+	maxSuffix int
+	globs     []int
+	// globPatterns holds the pattern to evaluate for each of globs, which is rewritten with foldGlob unless the
+	// matcher is case-sensitive
+	globPatterns []string
+}
+
+func newFilenameIndex(matchers []FilenameMatcher) *filenameIndex {
+	index := &filenameIndex{
+		literals: make(map[string][]int),
+		suffixes: make(map[string][]int),
+	}
+	for i, m := range matchers {
+		switch classifyGlob(m.Pattern) {
+		case globLiteral:
+			key := strings.ToLower(m.Pattern)
+			index.literals[key] = append(index.literals[key], i)
+		case globSuffix:
+			key := strings.ToLower(m.Pattern[1:])
+			index.suffixes[key] = append(index.suffixes[key], i)
+			index.maxSuffix = max(index.maxSuffix, len(key))
+		default:
+			pattern := m.Pattern
+			if !m.CaseSensitive {
+				pattern = foldGlob(pattern)
+			}
+			index.globs = append(index.globs, i)
+			index.globPatterns = append(index.globPatterns, pattern)
+		}
+	}
+	return index
+}
+
+// foldGlob rewrites a glob pattern so that it matches names regardless of case, by replacing each letter with a
+// class of both of its cases. For example, "README*" becomes "[rR][eE][aA][dD][mM][eE]*", and "*.[a-c]" becomes
+// "*.[a-cA-C]". This avoids converting every filename to lower-case before evaluating the pattern.
+func foldGlob(pattern string) string {
+	var b strings.Builder
+	runes := []rune(pattern)
+	inClass := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			r = runes[i]
+			switch {
+			case unicode.ToLower(r) == unicode.ToUpper(r):
+				b.WriteRune('\\')
+				b.WriteRune(r)
+			case inClass:
+				b.WriteRune(unicode.ToLower(r))
+				b.WriteRune(unicode.ToUpper(r))
+			default:
+				writeFoldedRune(&b, r)
+			}
+		case inClass && r == ']':
+			inClass = false
+			b.WriteRune(r)
+		case inClass && i+2 < len(runes) && runes[i+1] == '-' && runes[i+2] != ']':
+			end := runes[i+2]
+			i += 2
+			b.WriteRune(r)
+			b.WriteRune('-')
+			b.WriteRune(end)
+			switch {
+			case unicode.IsLower(r) && unicode.IsLower(end):
+				b.WriteRune(unicode.ToUpper(r))
+				b.WriteRune('-')
+				b.WriteRune(unicode.ToUpper(end))
+			case unicode.IsUpper(r) && unicode.IsUpper(end):
+				b.WriteRune(unicode.ToLower(r))
+				b.WriteRune('-')
+				b.WriteRune(unicode.ToLower(end))
+			}
+		case inClass:
+			b.WriteRune(r)
+			if lower, upper := unicode.ToLower(r), unicode.ToUpper(r); lower != r {
+				b.WriteRune(lower)
+			} else if upper != r {
+				b.WriteRune(upper)
+			}
+		case r == '[':
+			inClass = true
+			b.WriteRune(r)
+			if i+1 < len(runes) && (runes[i+1] == '!' || runes[i+1] == '^') {
+				i++
+				b.WriteRune(runes[i])
+			}
+		case unicode.ToLower(r) != unicode.ToUpper(r):
+			writeFoldedRune(&b, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func writeFoldedRune(b *strings.Builder, r rune) {
+	b.WriteRune('[')
+	b.WriteRune(unicode.ToLower(r))
+	b.WriteRune(unicode.ToUpper(r))
+	b.WriteRune(']')
+}
+
+// matchFilename returns the best filename matches for the provided filename, following the glob matching rules
+// of the freedesktop shared-mime-info specification:
+//
+//   - patterns are matched case-insensitively, unless they are marked as case-sensitive
+//   - if any literal pattern (e.g. "Makefile") matches, only literal patterns are considered
+//   - otherwise, suffix patterns (e.g. "*.gif") and full glob patterns (e.g. "README*") are considered
+//   - of the matching patterns, only those with the highest priority are kept
+//   - of those, only those with the longest pattern are kept
+func (rules *ruleTable) matchFilename(filename string) []FilenameMatcher {
+	var buffer [16]int
+	hits := rules.matchFilenameHits(filename, buffer[:0])
+	if len(hits) == 0 {
+		return nil
+	}
+	matches := make([]FilenameMatcher, len(hits))
+	for n, i := range hits {
+		matches[n] = rules.filenameMatchers[i]
+	}
+	return matches
+}
+
+// matchFilenameHits is like matchFilename, but appends the indices of the best matches to hits, in rule order,
+// rather than allocating a new slice of matchers.
+func (rules *ruleTable) matchFilenameHits(filename string, hits []int) []int {
+	filename = filepath.Base(filename)
+	index := rules.filenameIndex
+
+	// map lookups with a converted byte slice as the key do not allocate, so lowering the filename into a buffer
+	// avoids allocating for all but the longest and non-ASCII filenames
+	var buffer [128]byte
+	lower := appendLowerASCII(buffer[:0], filename)
+	if lower == nil {
+		lower = []byte(strings.ToLower(filename))
+	}
+
+	start := len(hits)
+	for _, i := range index.literals[string(lower)] {
+		if m := &rules.filenameMatchers[i]; !m.CaseSensitive || filename == m.Pattern {
+			hits = append(hits, i)
+		}
+	}
+
+	if len(hits) == start {
+		for offset := max(0, len(lower)-index.maxSuffix); offset < len(lower); offset++ {
+			for _, i := range index.suffixes[string(lower[offset:])] {
+				if m := &rules.filenameMatchers[i]; !m.CaseSensitive || strings.HasSuffix(filename, m.Pattern[1:]) {
+					hits = append(hits, i)
+				}
+			}
+		}
+		for n, i := range index.globs {
+			if ok, _ := doublestar.Match(index.globPatterns[n], filename); ok {
+				hits = append(hits, i)
+			}
+		}
+		// keep the matches in rule order, as ties are broken by whichever rule comes first
+		slices.Sort(hits[start:])
+	}
+
+	return append(hits[:start], bestFilenameHits(rules.filenameMatchers, hits[start:])...)
+}
+
+// appendLowerASCII appends the lower-case form of s to b, returning nil if s is not entirely ASCII.
+func appendLowerASCII(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			return nil
+		}
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// bestFilenameHits keeps the indices of the matches with the highest priority and, of those, the longest pattern.
+// The hits are filtered in place, preserving their relative order.
+func bestFilenameHits(matchers []FilenameMatcher, hits []int) []int {
+	if len(hits) == 0 {
+		return hits
+	}
+	maxPriority, maxLength := matchers[hits[0]].Priority, 0
+	for _, i := range hits {
+		maxPriority = max(maxPriority, matchers[i].Priority)
+	}
+	for _, i := range hits {
+		if matchers[i].Priority == maxPriority {
+			maxLength = max(maxLength, len(matchers[i].Pattern))
+		}
+	}
+	best := hits[:0]
+	for _, i := range hits {
+		if matchers[i].Priority == maxPriority && len(matchers[i].Pattern) == maxLength {
+			best = append(best, i)
+		}
+	}
+	return best
+}
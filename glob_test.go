@@ -0,0 +1,183 @@
+package magic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIdentifyWithFilenameGlobRules(t *testing.T) {
+
+	tests := []struct {
+		filename     string
+		expectedMIME string
+	}{
+		{
+			filename:     "photo.JPG",
+			expectedMIME: "image/jpeg",
+		},
+		{
+			filename:     "README.TXT",
+			expectedMIME: "text/plain",
+		},
+		{
+			filename:     "main.c",
+			expectedMIME: "text/x-csrc",
+		},
+		{
+			filename:     "main.C",
+			expectedMIME: "text/x-c++src",
+		},
+		{
+			filename:     "makefile",
+			expectedMIME: "text/x-makefile",
+		},
+		{
+			filename:     "core",
+			expectedMIME: "application/x-core",
+		},
+		{
+			filename:     "CORE",
+			expectedMIME: "text/plain",
+		},
+		{
+			filename:     "archive.tar.gz",
+			expectedMIME: "application/x-compressed-tar",
+		},
+		{
+			filename:     "ARCHIVE.TAR.GZ",
+			expectedMIME: "application/x-compressed-tar",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			fileType := IdentifyWithFilename(bytes.NewBuffer(nil), test.filename)
+			assert.Equal(t, fileType.MIME, test.expectedMIME)
+		})
+	}
+}
+
+func TestClassifyGlob(t *testing.T) {
+	assert.Equal(t, classifyGlob("Makefile"), globLiteral)
+	assert.Equal(t, classifyGlob("*.gif"), globSuffix)
+	assert.Equal(t, classifyGlob("*.tar.gz"), globSuffix)
+	assert.Equal(t, classifyGlob("README*"), globFull)
+	assert.Equal(t, classifyGlob("*.[ch]"), globFull)
+}
+
+func TestFoldGlob(t *testing.T) {
+	assert.Equal(t, foldGlob("README*"), "[rR][eE][aA][dD][mM][eE]*")
+	assert.Equal(t, foldGlob("*.[ch]"), "*.[cChH]")
+	assert.Equal(t, foldGlob("*.[a-c]"), "*.[a-cA-C]")
+	assert.Equal(t, foldGlob("*.[0-9]"), "*.[0-9]")
+	assert.Equal(t, foldGlob("*.\\*"), "*.\\*")
+}
+
+func TestMatchFilenameLiteralsFirst(t *testing.T) {
+	rules := NewDetector(RuleSet{
+		FilenameMatchers: []FilenameMatcher{
+			{
+				Pattern:  "*.txt",
+				Result:   FileType{MIME: "text/plain"},
+				Priority: 80,
+			},
+			{
+				Pattern:  "notes.txt",
+				Result:   FileType{MIME: "text/x-notes"},
+				Priority: 50,
+			},
+		},
+	}).rules.Load()
+
+	matches := rules.matchFilename("NOTES.TXT")
+	assert.Equal(t, len(matches), 1)
+	assert.Equal(t, matches[0].Result.MIME, "text/x-notes")
+
+	matches = rules.matchFilename("other.txt")
+	assert.Equal(t, len(matches), 1)
+	assert.Equal(t, matches[0].Result.MIME, "text/plain")
+}
+
+func TestFilenameIndexMatchesLinearScan(t *testing.T) {
+	rules := defaultDetector.rules.Load()
+
+	linear := func(filename string) []FilenameMatcher {
+		lower := strings.ToLower(filename)
+		var literals, others []int
+		for i, m := range rules.filenameMatchers {
+			if !m.match(filename, lower) {
+				continue
+			}
+			if classifyGlob(m.Pattern) == globLiteral {
+				literals = append(literals, i)
+			} else {
+				others = append(others, i)
+			}
+		}
+		hits := bestFilenameHits(rules.filenameMatchers, others)
+		if len(literals) > 0 {
+			hits = bestFilenameHits(rules.filenameMatchers, literals)
+		}
+		var matches []FilenameMatcher
+		for _, i := range hits {
+			matches = append(matches, rules.filenameMatchers[i])
+		}
+		return matches
+	}
+
+	var filenames []string
+	for _, m := range rules.filenameMatchers {
+		name := strings.NewReplacer("*", "name", "?", "x", "[", "", "]", "").Replace(m.Pattern)
+		filenames = append(filenames, name, strings.ToUpper(name), "prefix-"+name)
+	}
+
+	for _, filename := range filenames {
+		assert.DeepEqual(t, rules.matchFilename(filename), linear(filename))
+	}
+}
+
+var benchmarkFilenames = []string{
+	"photo.JPG",
+	"archive.tar.gz",
+	"Makefile",
+	"README",
+	"main.go",
+	"no-extension",
+	"document.unknown-extension",
+}
+
+func BenchmarkIdentifyWithFilename(b *testing.B) {
+	empty := bytes.NewReader(nil)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		empty.Reset(nil)
+		_ = IdentifyWithFilename(empty, benchmarkFilenames[i%len(benchmarkFilenames)])
+	}
+}
+
+func TestIdentifyBytesWithFilenameDoesNotAllocate(t *testing.T) {
+	for _, filename := range benchmarkFilenames {
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = IdentifyBytesWithFilename(nil, filename)
+		})
+		assert.Equal(t, allocs, float64(0), filename)
+	}
+}
+
+func BenchmarkIdentifyBytesWithFilename(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		_ = IdentifyBytesWithFilename(nil, benchmarkFilenames[i%len(benchmarkFilenames)])
+	}
+}
+
+func BenchmarkMatchFilename(b *testing.B) {
+	rules := defaultDetector.rules.Load()
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		_ = rules.matchFilename(benchmarkFilenames[i%len(benchmarkFilenames)])
+	}
+}
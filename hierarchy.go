@@ -0,0 +1,57 @@
+package magic
+
+import (
+	"slices"
+	"strings"
+)
+
+var (
+	implicitTextParents   = []string{"text/plain"}
+	implicitBinaryParents = []string{"application/octet-stream"}
+)
+
+// Parents returns the MIME types which the provided MIME type is a direct sub-class of, according to the
+// freedesktop shared-mime-info database. Types without an explicit parent follow the rules implied by the
+// specification: every text/* type is a sub-class of text/plain, and every other type (apart from inode/* types)
+// is a sub-class of application/octet-stream. Aliases are resolved with Canonical.
+func Parents(mime string) []string {
+	return append([]string(nil), parentsOf(Canonical(mime))...)
+}
+
+func parentsOf(mime string) []string {
+	if parents, ok := subClassOf[mime]; ok {
+		return parents
+	}
+	switch {
+	case mime == "application/octet-stream", strings.HasPrefix(mime, "inode/"):
+		return nil
+	case strings.HasPrefix(mime, "text/") && mime != "text/plain":
+		return implicitTextParents
+	default:
+		return implicitBinaryParents
+	}
+}
+
+// IsA reports whether the provided MIME type is the same as, or a sub-class of, the ancestor MIME type.
+// For example, every OOXML document is a zip archive, and SVG images are XML. Aliases are resolved with
+// Canonical.
+func IsA(mime, ancestor string) bool {
+	mime, ancestor = Canonical(mime), Canonical(ancestor)
+	if mime == ancestor {
+		return true
+	}
+	// the hierarchy is shallow, so the queue doubles as the set of visited types and rarely outgrows the stack
+	var buf [16]string
+	queue := append(buf[:0], parentsOf(mime)...)
+	for i := 0; i < len(queue); i++ {
+		current := queue[i]
+		if current == ancestor {
+			return true
+		}
+		if current == mime || slices.Contains(queue[:i], current) {
+			continue
+		}
+		queue = append(queue, parentsOf(current)...)
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package magic
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIsA(t *testing.T) {
+
+	tests := []struct {
+		mime     string
+		ancestor string
+		expected bool
+	}{
+		{
+			mime:     "image/png",
+			ancestor: "image/png",
+			expected: true,
+		},
+		{
+			mime:     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			ancestor: "application/zip",
+			expected: true,
+		},
+		{
+			mime:     "image/svg+xml",
+			ancestor: "application/xml",
+			expected: true,
+		},
+		{
+			mime:     "image/svg+xml",
+			ancestor: "text/plain",
+			expected: true,
+		},
+		{
+			mime:     "text/csv",
+			ancestor: "text/plain",
+			expected: true,
+		},
+		{
+			mime:     "text/x-made-up",
+			ancestor: "text/plain",
+			expected: true,
+		},
+		{
+			mime:     "image/png",
+			ancestor: "application/octet-stream",
+			expected: true,
+		},
+		{
+			mime:     "text/plain",
+			ancestor: "application/octet-stream",
+			expected: true,
+		},
+		{
+			mime:     "inode/directory",
+			ancestor: "application/octet-stream",
+			expected: false,
+		},
+		{
+			mime:     "application/zip",
+			ancestor: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			expected: false,
+		},
+		{
+			mime:     "image/png",
+			ancestor: "text/plain",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mime+" is "+test.ancestor, func(t *testing.T) {
+			assert.Equal(t, IsA(test.mime, test.ancestor), test.expected)
+		})
+	}
+}
+
+func TestParents(t *testing.T) {
+	assert.DeepEqual(t, Parents("image/svg+xml"), []string{"application/xml"})
+	assert.DeepEqual(t, Parents("text/x-made-up"), []string{"text/plain"})
+	assert.DeepEqual(t, Parents("image/png"), []string{"application/octet-stream"})
+	assert.Equal(t, len(Parents("application/octet-stream")), 0)
+	assert.Equal(t, len(Parents("inode/directory")), 0)
+}
+
+func TestIsAWithAliases(t *testing.T) {
+	assert.Assert(t, IsA("application/x-gzip", "application/gzip"))
+	assert.Assert(t, IsA("audio/vnd.wave", "audio/x-wav"))
+	assert.Assert(t, IsA("application/ld+json", "application/x-javascript"))
+}
@@ -0,0 +1,57 @@
+package magic
+
+import "io/fs"
+
+// The freedesktop types of files which are identified by their kind rather than their content. Directories are
+// identified by IdentifyDir.
+var (
+	symlinkFileType = FileType{
+		Description: "Symbolic link",
+		MIME:        "inode/symlink",
+		Icon:        "inode-symlink",
+	}
+	fifoFileType = FileType{
+		Description: "Pipe",
+		MIME:        "inode/fifo",
+		Icon:        "inode-fifo",
+	}
+	socketFileType = FileType{
+		Description: "Socket",
+		MIME:        "inode/socket",
+		Icon:        "inode-socket",
+	}
+	blockDeviceFileType = FileType{
+		Description: "Block device",
+		MIME:        "inode/blockdevice",
+		Icon:        "inode-blockdevice",
+	}
+	charDeviceFileType = FileType{
+		Description: "Character device",
+		MIME:        "inode/chardevice",
+		Icon:        "inode-chardevice",
+	}
+	emptyFileType = FileType{
+		Description: "Empty document",
+		MIME:        "application/x-zerosize",
+		Icon:        "application-x-zerosize",
+	}
+)
+
+// identifyMode returns the type of a file which can be identified from its mode alone, and whether it can be. Reading
+// such files would either block, as with a pipe or socket, or have side effects, as with a device.
+func identifyMode(mode fs.FileMode) (FileType, bool) {
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return symlinkFileType, true
+	case mode&fs.ModeNamedPipe != 0:
+		return fifoFileType, true
+	case mode&fs.ModeSocket != 0:
+		return socketFileType, true
+	case mode&fs.ModeCharDevice != 0:
+		return charDeviceFileType, true
+	case mode&fs.ModeDevice != 0:
+		return blockDeviceFileType, true
+	default:
+		return FileType{}, false
+	}
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package magic
+
+import (
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIdentifyPathSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	fifo := filepath.Join(dir, "fifo")
+	assert.NilError(t, syscall.Mkfifo(fifo, 0o600))
+	socket := filepath.Join(dir, "socket")
+	l, err := net.Listen("unix", socket)
+	assert.NilError(t, err)
+	defer func() { _ = l.Close() }()
+
+	tests := []struct {
+		path         string
+		expectedMIME string
+	}{
+		// reading a pipe with no writer would block
+		{path: fifo, expectedMIME: "inode/fifo"},
+		{path: socket, expectedMIME: "inode/socket"},
+		{path: "/dev/null", expectedMIME: "inode/chardevice"},
+	}
+
+	for _, test := range tests {
+		t.Run(filepath.Base(test.path), func(t *testing.T) {
+			ft, err := IdentifyPath(test.path)
+			assert.NilError(t, err)
+			assert.Equal(t, ft.MIME, test.expectedMIME)
+		})
+	}
+}
+
+func TestIdentifyDirDoesNotReadSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	// reading a pipe with no writer would block
+	assert.NilError(t, syscall.Mkfifo(filepath.Join(dir, "autorun.txt"), 0o600))
+	d := NewDetector(RuleSet{TreeMatchers: []TreeMatcher{{
+		Submatches: []TreeSubMatcher{{Path: "autorun.txt", MIMEType: "text/plain"}},
+		Result:     FileType{MIME: "x-content/custom"},
+		Priority:   50,
+	}}})
+
+	ft, err := d.IdentifyDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "inode/directory")
+}
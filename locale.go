@@ -0,0 +1,83 @@
+package magic
+
+import "strings"
+
+// DescriptionFor returns the description of the file type translated into the provided language, falling back
+// to the English description if no translation is available. The language may be given as a BCP 47 tag such
+// as "pt-BR", or as a POSIX locale such as "pt_BR.UTF-8". If there is no translation for a specific region, the
+// translation for the language alone is used. Descriptions other than the freedesktop one for the type, such as
+// those of custom rules, are never translated.
+func (f FileType) DescriptionFor(lang string) string {
+	translations := descriptionTranslations[f.MIME]
+	if len(translations) == 0 || f.Description != untranslatedDescriptions[f.MIME] {
+		return f.Description
+	}
+	for _, key := range localeKeys(lang) {
+		if description, ok := translations[key]; ok {
+			return description
+		}
+	}
+	return f.Description
+}
+
+// localeKeys converts a language tag into the keys used by freedesktop translations (e.g. "pt_BR", "sr@latin"),
+// from most to least specific.
+func localeKeys(lang string) []string {
+	var modifier string
+	if i := strings.IndexByte(lang, '@'); i >= 0 {
+		lang, modifier = lang[:i], lang[i+1:]
+	}
+	if i := strings.IndexByte(lang, '.'); i >= 0 {
+		lang = lang[:i]
+	}
+
+	parts := strings.FieldsFunc(lang, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	if len(parts) == 0 {
+		return nil
+	}
+
+	language := strings.ToLower(parts[0])
+	if language == "c" || language == "posix" {
+		return nil
+	}
+
+	var script, region string
+	for _, part := range parts[1:] {
+		switch {
+		case len(part) == 4 && script == "" && region == "":
+			script = strings.ToLower(part)
+		case (len(part) == 2 || len(part) == 3) && region == "":
+			region = strings.ToUpper(part)
+		}
+	}
+
+	// freedesktop uses regions rather than scripts for Chinese, and modifiers for other scripts
+	switch script {
+	case "hant":
+		if region == "" {
+			region = "TW"
+		}
+	case "hans":
+		if region == "" {
+			region = "CN"
+		}
+	case "latn":
+		if modifier == "" {
+			modifier = "latin"
+		}
+	}
+
+	keys := make([]string, 0, 4)
+	if region != "" && modifier != "" {
+		keys = append(keys, language+"_"+region+"@"+modifier)
+	}
+	if region != "" {
+		keys = append(keys, language+"_"+region)
+	}
+	if modifier != "" {
+		keys = append(keys, language+"@"+modifier)
+	}
+	return append(keys, language)
+}
@@ -0,0 +1,91 @@
+package magic
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDescriptionFor(t *testing.T) {
+	pdf := FileType{
+		Description: "PDF document",
+		MIME:        "application/pdf",
+	}
+
+	tests := []struct {
+		lang     string
+		expected string
+	}{
+		{
+			lang:     "de",
+			expected: "PDF-Dokument",
+		},
+		{
+			lang:     "de-AT",
+			expected: "PDF-Dokument",
+		},
+		{
+			lang:     "de_DE.UTF-8",
+			expected: "PDF-Dokument",
+		},
+		{
+			lang:     "ja-JP",
+			expected: "PDF ドキュメント",
+		},
+		{
+			lang:     "pt-BR",
+			expected: "Documento PDF",
+		},
+		{
+			lang:     "pt-PT",
+			expected: "documento PDF",
+		},
+		{
+			lang:     "zh-Hant",
+			expected: "PDF 文件",
+		},
+		{
+			lang:     "en",
+			expected: pdf.Description,
+		},
+		{
+			lang:     "C",
+			expected: pdf.Description,
+		},
+		{
+			lang:     "",
+			expected: pdf.Description,
+		},
+		{
+			lang:     "xx-YY",
+			expected: pdf.Description,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.lang, func(t *testing.T) {
+			assert.Assert(t, test.expected != "")
+			assert.Equal(t, pdf.DescriptionFor(test.lang), test.expected)
+		})
+	}
+}
+
+func TestDescriptionForUnknownType(t *testing.T) {
+	assert.Equal(t, unknownBinaryFileType.DescriptionFor("de"), unknownBinaryFileType.Description)
+}
+
+func TestDescriptionForCustomDescription(t *testing.T) {
+	gomod := FileType{
+		Description: "Go modules configuration file",
+		MIME:        "text/x-go",
+	}
+	assert.Assert(t, len(descriptionTranslations[gomod.MIME]) > 0)
+	assert.Equal(t, gomod.DescriptionFor("de"), gomod.Description)
+}
+
+func TestLocaleKeys(t *testing.T) {
+	assert.DeepEqual(t, localeKeys("pt-BR"), []string{"pt_BR", "pt"})
+	assert.DeepEqual(t, localeKeys("sr-Latn-RS"), []string{"sr_RS@latin", "sr_RS", "sr@latin", "sr"})
+	assert.DeepEqual(t, localeKeys("be_BY.UTF-8@latin"), []string{"be_BY@latin", "be_BY", "be@latin", "be"})
+	assert.Equal(t, len(localeKeys("POSIX")), 0)
+}
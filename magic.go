@@ -1,17 +1,18 @@
 package magic
 
-//go:generate go run cmd/generator/main.go freedesktop.org.xml generated.go
+//go:generate go run cmd/generator/main.go -translations po freedesktop.org.xml generated.go
 
 import (
 	"bytes"
-	"fmt"
+	"context"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
+	"strings"
 	"unicode/utf8"
-
-	"github.com/bmatcuk/doublestar/v4"
 )
 
 var unknownBinaryFileType = FileType{
@@ -28,69 +29,176 @@ var unknownTextFileType = FileType{
 	Icon:                 "text-x-generic",
 }
 
-type bufferedReader struct {
-	reader io.Reader
-	buffer []byte
+// Identify looks up the file type based on the provided bytes. See Detector.Identify.
+func Identify(r io.Reader) FileType {
+	return defaultDetector.Identify(r)
 }
 
-func (b *bufferedReader) MaybeBuffer(length int) {
-	_ = b.EnsureBuffered(length)
+// Identify looks up the file type based on the provided bytes. The rule with the highest priority which matches is
+// used. If several rules of that priority match, the one which matches the most bytes is used and, of those, one
+// whose type is a sub-class of all the others. Otherwise the rule which comes first is used, keeping the order of
+// the rules given to NewDetector.
+func (d *Detector) Identify(r io.Reader) FileType {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	return d.rules.Load().identify(b, nil)
 }
 
-func (b *bufferedReader) EnsureBuffered(length int) error {
-	if len(b.buffer) >= length {
-		return nil
-	}
-	extra := length - len(b.buffer)
-	existing := b.buffer
+// IdentifyBytes looks up the file type based on the provided bytes. See Detector.IdentifyBytes.
+func IdentifyBytes(data []byte) FileType {
+	return defaultDetector.IdentifyBytes(data)
+}
 
-	b.buffer = make([]byte, length)
-	if len(existing) > 0 {
-		copy(b.buffer, existing)
-	}
+// IdentifyBytes looks up the file type based on the provided bytes, which are the start of the content, such as its
+// first few kilobytes. As the end of data is not taken to be the end of the content, rules anchored to the end of
+// the content, such as those for ZIP archives and VHD disk images, are skipped. Use IdentifyWholeBytes if data is
+// the whole content. It does not allocate, unless the content is XML.
+func (d *Detector) IdentifyBytes(data []byte) FileType {
+	b := d.bufferPrefix(data)
+	return d.rules.Load().identify(&b, nil)
+}
 
-	if n, err := b.reader.Read(b.buffer[len(existing):]); err != nil {
-		b.buffer = b.buffer[:len(existing)]
-		return err
-	} else if n < extra {
-		b.buffer = b.buffer[:len(existing)+n]
-		return fmt.Errorf("not enough data available to read")
-	}
+// IdentifyBytesWithFilename is like IdentifyWithFilename, but reads the content from the provided bytes. See
+// Detector.IdentifyBytesWithFilename.
+func IdentifyBytesWithFilename(data []byte, filename string) FileType {
+	return defaultDetector.IdentifyBytesWithFilename(data, filename)
+}
 
-	return nil
+// IdentifyBytesWithFilename is like IdentifyWithFilename, but reads the content from the provided bytes, which are
+// the start of the content, like IdentifyBytes.
+func (d *Detector) IdentifyBytesWithFilename(data []byte, filename string) FileType {
+	b := d.bufferPrefix(data)
+	return d.identifyWithFilename(d.rules.Load(), &b, filename, nil)
 }
 
-func (b *bufferedReader) Data() []byte {
-	return b.buffer
+// IdentifyWholeBytes looks up the file type based on the whole content. See Detector.IdentifyWholeBytes.
+func IdentifyWholeBytes(data []byte) FileType {
+	return defaultDetector.IdentifyWholeBytes(data)
 }
 
-// Identify looks up the file type based on the provided bytes.
-func Identify(r io.Reader) FileType {
+// IdentifyWholeBytes is like IdentifyBytes, but data is the whole content, so rules anchored to the end of the
+// content are matched against the end of data.
+func (d *Detector) IdentifyWholeBytes(data []byte) FileType {
+	b := d.bufferBytes(data)
+	return d.rules.Load().identify(&b, nil)
+}
 
-	b := &bufferedReader{
-		reader: r,
-	}
+// IdentifyWholeBytesWithFilename is like IdentifyBytesWithFilename, but for the whole content. See
+// Detector.IdentifyWholeBytesWithFilename.
+func IdentifyWholeBytesWithFilename(data []byte, filename string) FileType {
+	return defaultDetector.IdentifyWholeBytesWithFilename(data, filename)
+}
+
+// IdentifyWholeBytesWithFilename is like IdentifyBytesWithFilename, but data is the whole content, like
+// IdentifyWholeBytes.
+func (d *Detector) IdentifyWholeBytesWithFilename(data []byte, filename string) FileType {
+	b := d.bufferBytes(data)
+	return d.identifyWithFilename(d.rules.Load(), &b, filename, nil)
+}
+
+// identify looks up the file type of the content, recording the rules it evaluates in trace unless it is nil.
+func (rules *ruleTable) identify(b *bufferedReader, trace *Explanation) FileType {
+	ft, _ := rules.identifyContent(b, trace)
+	return ft
+}
 
-	for _, t := range allDataMatchers {
-		if t.MatchBytes(b) {
-			return t.Result
+// identifyContent is like identify, but also returns the priority of the magic rule which identified the content.
+// The priority is zero if no magic rule matched, even if the root element of an XML document did.
+func (rules *ruleTable) identifyContent(b *bufferedReader, trace *Explanation) (FileType, int) {
+	// rules are evaluated in order of priority, so the rules which share the priority of the first to match are
+	// weighed against each other, and the rest are skipped
+	var buffer [16]*DataMatcher
+	tied := buffer[:0]
+	rules.dataIndex.match(b, true, func(m *DataMatcher) bool {
+		tied = append(tied, m)
+		return true
+	})
+	// rules are weighed and explained using the content which was read to identify it
+	c := b.available()
+	result := mostSpecific(tied, &c)
+	trace.traceData(rules.dataIndex, &c, result)
+	if result != nil {
+		// XML documents can be identified more specifically by their root element
+		if IsA(result.Result.MIME, "application/xml") {
+			if m := rules.matchXMLRoot(b); m != nil {
+				trace.traceXMLRoot(m)
+				return m.Result, result.Priority
+			}
 		}
+		return result.Result, result.Priority
 	}
-	return identifyUnknownType(b)
+	if m := rules.matchXMLRoot(b); m != nil {
+		trace.traceXMLRoot(m)
+		return m.Result, 0
+	}
+	return identifyUnknownType(b), 0
 }
 
+// IdentifyPath looks up the file type of the file at the provided path. See Detector.IdentifyPath.
 func IdentifyPath(path string) (FileType, error) {
+	return defaultDetector.IdentifyPath(path)
+}
+
+// IdentifyPath looks up the file type of the file at the provided path, using both its name and content. The content
+// is read like IdentifyReaderAt, and directories are identified using IdentifyDir. Pipes, sockets and devices are
+// identified as inode/fifo, inode/socket, inode/blockdevice or inode/chardevice without being read, and empty files
+// as application/x-zerosize. Symbolic links are followed, unless WithoutFollowingSymlinks is set, and are identified
+// as inode/symlink if they are not followed or their target does not exist. Errors are returned like Sniff.
+func (d *Detector) IdentifyPath(path string) (FileType, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return unknownBinaryFileType, err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 && !d.noFollowSymlinks {
+		target, err := os.Stat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return symlinkFileType, nil
+		case err != nil:
+			return unknownBinaryFileType, err
+		}
+		info = target
+	}
+	if info.IsDir() {
+		return d.IdentifyDir(path)
+	}
+	if ft, ok := identifyMode(info.Mode()); ok {
+		return ft, nil
+	}
+	if info.Mode().IsRegular() && info.Size() == 0 {
+		return emptyFileType, nil
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return unknownBinaryFileType, err
 	}
 	defer func() { _ = f.Close() }()
-	return IdentifyWithFilename(f, filepath.Base(path)), nil
+	rules := d.rules.Load()
+	b, done := d.bufferAt(context.Background(), f, info.Size(), rules.dataIndex.tailExtent)
+	defer done()
+	ft := d.identifyWithFilename(rules, b, filepath.Base(path), nil)
+	return ft, contentErr(b, ft)
+}
+
+// IdentifyReaderAt looks up the file type of content of a known size. See Detector.IdentifyReaderAt.
+func IdentifyReaderAt(r io.ReaderAt, size int64) FileType {
+	return defaultDetector.IdentifyReaderAt(r, size)
+}
+
+// IdentifyReaderAt looks up the file type based on the provided content of the given size. Unlike Identify, rules
+// which look for signatures at the end of the content, such as the footer of a disk image, can be evaluated without
+// reading the rest of the content.
+func (d *Detector) IdentifyReaderAt(r io.ReaderAt, size int64) FileType {
+	rules := d.rules.Load()
+	b, done := d.bufferAt(context.Background(), r, size, rules.dataIndex.tailExtent)
+	defer done()
+	return rules.identify(b, nil)
 }
 
 func identifyUnknownType(b *bufferedReader) FileType {
-	// we just want to fill the buffer with anything up to 128 bytes
-	b.MaybeBuffer(128)
+	if looksBinary(b) {
+		return unknownBinaryFileType
+	}
 	for i := range len(b.Data()) {
 		if b.Data()[i] < 32 || b.Data()[i] > 126 {
 			if utf8.Valid(b.Data()) {
@@ -102,78 +210,190 @@ func identifyUnknownType(b *bufferedReader) FileType {
 	return unknownTextFileType
 }
 
-// IdentifyWithFilename looks up the file type based on the provided filename, falling back to the bytes if needed.
-// See https://specifications.freedesktop.org/shared-mime-info/latest/ar01s02.html#id-1.3.15 for checking order
-func IdentifyWithFilename(r io.Reader, filename string) FileType {
-	filename = filepath.Base(filename)
-	candidates := make([]FilenameMatcher, 0)
-	maxPriority := 0
-	for _, t := range allFilenameMatchers {
-		if t.Priority < maxPriority {
-			continue
+// looksBinary reports whether the start of the content contains ASCII control characters other than whitespace, as
+// the freedesktop specification suggests for telling binary content from text, even though they are valid UTF-8.
+func looksBinary(b *bufferedReader) bool {
+	// we just want to fill the buffer with anything up to 128 bytes
+	b.MaybeBuffer(128)
+	data := b.Data()
+	for _, c := range data[:min(len(data), 128)] {
+		if (c < 0x20 || c == 0x7f) && !strings.ContainsRune("\b\t\n\v\f\r", rune(c)) {
+			return true
 		}
-		if ok, _ := doublestar.Match(t.Pattern, filename); ok {
-			if t.Priority > maxPriority {
-				maxPriority = t.Priority
+	}
+	return false
+}
+
+// IdentifyWithFilename looks up the file type based on the provided filename and bytes, weighing them against each
+// other in the order recommended by the freedesktop specification:
+//
+//   - if the content is binary, patterns for text types are ignored
+//   - a magic rule of priority 80 or more overrides the filename
+//   - otherwise, a type suggested by the filename is used if the content is that type or a more general one
+//   - the content is used if it is a more specific type than the filename suggests
+//   - otherwise, the first of the types suggested by the filename is used
+//
+// If no pattern matches the filename, the content alone is used. See WithLegacyFilenameMatching for the behaviour of
+// earlier versions, and https://specifications.freedesktop.org/shared-mime-info/latest/ar01s02.html#id-1.3.15 for
+// the checking order.
+func IdentifyWithFilename(r io.Reader, filename string) FileType {
+	return defaultDetector.IdentifyWithFilename(r, filename)
+}
+
+// IdentifyWithFilename looks up the file type based on the provided filename and bytes, weighing them against each
+// other in the order recommended by the freedesktop specification. See the package-level IdentifyWithFilename for
+// details.
+func (d *Detector) IdentifyWithFilename(r io.Reader, filename string) FileType {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	return d.identifyWithFilename(d.rules.Load(), b, filename, nil)
+}
+
+// identifyWithFilename looks up the file type using both the filename and the content, in the order configured for
+// the detector, recording the rules it evaluates in trace unless it is nil.
+func (d *Detector) identifyWithFilename(rules *ruleTable, b *bufferedReader, filename string, trace *Explanation) FileType {
+	if d.legacyFilenameMatching {
+		return rules.identifyWithFilenameLegacy(b, filename, trace)
+	}
+	return rules.identifyWithFilename(b, filename, trace)
+}
+
+// strongMagicPriority is the priority from which a magic rule overrides the filename, as file managers do.
+const strongMagicPriority = 80
+
+// identifyWithFilename weighs the filename against the content as the freedesktop specification recommends.
+func (rules *ruleTable) identifyWithFilename(b *bufferedReader, filename string, trace *Explanation) FileType {
+	var buffer [16]int
+	globs := rules.matchFilenameHits(filename, buffer[:0])
+	trace.traceFilenames(rules, filename, globs)
+	if len(globs) == 0 {
+		trace.decide("no pattern matches the filename, so the content was used")
+		return rules.identify(b, trace)
+	}
+
+	ft, priority := rules.identifyContent(b, trace)
+	if looksBinary(b) {
+		const reason = "the content is binary, so it cannot be a text type"
+		kept := globs[:0]
+		for n, i := range globs {
+			if IsA(rules.filenameMatchers[i].Result.MIME, "text/plain") {
+				trace.rejectGlobs(rules, globs[n:n+1], reason)
+			} else {
+				kept = append(kept, i)
 			}
-			candidates = append(candidates, t)
+		}
+		if globs = kept; len(globs) == 0 {
+			trace.decide(reason + ", whatever the filename suggests")
+			return ft
 		}
 	}
-	if len(candidates) > 0 {
-		filtered := make([]FilenameMatcher, 0, len(candidates))
-		for _, c := range candidates {
-			if c.Priority == maxPriority {
-				filtered = append(filtered, c)
-			}
+
+	if ft != unknownBinaryFileType && ft != unknownTextFileType {
+		if priority >= strongMagicPriority {
+			const reason = "a magic rule of priority 80 or more matches the content, which overrides the filename"
+			trace.decide(reason)
+			trace.rejectGlobs(rules, globs, reason)
+			return ft
 		}
-		if len(filtered) == 1 {
-			return filtered[0].Result
-		}
-		sort.Slice(filtered, func(i, j int) bool {
-			return filtered[i].Pattern > filtered[j].Pattern
-		})
-		maxPatternLength := len(filtered[0].Pattern)
-		refiltered := make([]FilenameMatcher, 0, len(filtered))
-		for _, f := range filtered {
-			if len(f.Pattern) == maxPatternLength {
-				refiltered = append(refiltered, f)
+		for n, i := range globs {
+			if IsA(rules.filenameMatchers[i].Result.MIME, ft.MIME) {
+				const reason = "the filename suggests the same or a more specific type than the content"
+				trace.decide(reason)
+				trace.acceptGlobs(rules, globs, n, "the filename suggests other types")
+				return rules.filenameMatchers[i].Result
 			}
 		}
-		mimes := make(map[string]struct{}, len(refiltered))
-		for _, f := range refiltered {
-			mimes[f.Result.MIME] = struct{}{}
-		}
-		if len(mimes) == 1 {
-			return refiltered[0].Result
+		for _, i := range globs {
+			if IsA(ft.MIME, rules.filenameMatchers[i].Result.MIME) {
+				const reason = "the content is a more specific type than the filename suggests"
+				trace.decide(reason)
+				trace.rejectGlobs(rules, globs, reason)
+				return ft
+			}
 		}
+		trace.decide("magic rules below priority 80 do not override the filename")
+	} else {
+		trace.decide("no magic rule matches the content, so the filename was used")
+	}
+	trace.acceptGlobs(rules, globs, 0, "patterns for several types match the filename, and the first was used")
+	return rules.filenameMatchers[globs[0]].Result
+}
 
-		// we follow the fressdesktop advice here of using the file content if there are multiple filename matches.
-		// however, if the file content doesn't yield a match either, we take the first filename match
-		fallback := Identify(r)
-		if fallback != unknownBinaryFileType && fallback != unknownTextFileType {
-			return fallback
-		}
+// identifyWithFilenameLegacy only uses the content if no pattern matches the filename, or if patterns for several
+// types do, as earlier versions did.
+func (rules *ruleTable) identifyWithFilenameLegacy(b *bufferedReader, filename string, trace *Explanation) FileType {
+	var buffer [16]int
+	globs := rules.matchFilenameHits(filename, buffer[:0])
+	trace.traceFilenames(rules, filename, globs)
+	if len(globs) == 0 {
+		trace.decide("no pattern matches the filename, so the content was used")
+		return rules.identify(b, trace)
+	}
+	first := &rules.filenameMatchers[globs[0]]
+	if !slices.ContainsFunc(globs[1:], func(i int) bool {
+		return rules.filenameMatchers[i].Result.MIME != first.Result.MIME
+	}) {
+		trace.decide("the filename matches patterns for a single type")
+		trace.acceptGlobs(rules, globs, 0, "")
+		return first.Result
+	}
 
-		return refiltered[0].Result
+	// we follow the fressdesktop advice here of using the file content if there are multiple filename matches.
+	// however, if the file content doesn't yield a match either, we take the first filename match
+	fallback := rules.identify(b, trace)
+	if fallback != unknownBinaryFileType && fallback != unknownTextFileType {
+		const reason = "patterns for several types match the filename, so the content decided between them"
+		trace.decide(reason)
+		trace.rejectGlobs(rules, globs, reason)
+		return fallback
 	}
-	return Identify(r)
+
+	const reason = "patterns for several types match the filename, and the content did not decide between them, " +
+		"so the first was used"
+	trace.decide(reason)
+	trace.acceptGlobs(rules, globs, 0, reason)
+	return first.Result
+}
+
+func uniqueMIMEs(matchers []FilenameMatcher) []string {
+	mimes := make([]string, 0, len(matchers))
+	seen := make(map[string]struct{}, len(matchers))
+	for _, m := range matchers {
+		if _, ok := seen[m.Result.MIME]; ok {
+			continue
+		}
+		seen[m.Result.MIME] = struct{}{}
+		mimes = append(mimes, m.Result.MIME)
+	}
+	return mimes
 }
 
 type FilenameMatcher struct {
 	Pattern  string
 	Result   FileType
 	Priority int
+	// CaseSensitive disables the default case-insensitive matching of Pattern.
+	CaseSensitive bool
+	// Origin records where the rule came from, for explanations. It is set by DefaultRules.
+	Origin RuleOrigin
 }
 
 type DataMatcher struct {
 	Submatches []DataSubMatcher
 	Result     FileType
 	Priority   int
+	// Origin records where the rule came from, for explanations. It is set by DefaultRules.
+	Origin RuleOrigin
 }
 
 type DataSubMatcher struct {
-	Bytes    []byte
-	Offsets  []int
+	Bytes []byte
+	// Offset is the position of Bytes from the start of the content or, if it is negative, from the end of the
+	// content. For example, an Offset of -4 matches Bytes against the last four bytes of the content.
+	Offset int
+	// Range is the number of further positions after Offset at which Bytes may start, so that Bytes is searched for
+	// anywhere from Offset to Offset+Range inclusive. It is zero if Bytes must be at exactly Offset.
+	Range    int
 	Mask     []byte
 	Children []DataSubMatcher
 }
@@ -184,59 +404,195 @@ type FileType struct {
 	RecommendedExtension string
 	Icon                 string
 	MIME                 string
+	// Acronym is the short name of the type, such as "PDF", if it has one.
+	Acronym string
+	// ExpandedAcronym is the long form of Acronym, such as "Portable Document Format".
+	ExpandedAcronym string
+}
+
+// mostSpecific chooses between rules of the same priority which all match the content, returning nil if there are
+// none. The rules which match the most bytes of the content are preferred and, of those, a rule whose type is a
+// sub-class of the types of all the others. If there is no such rule, such as when the rules identify sibling types
+// equally well, the first of them is used.
+func mostSpecific(rules []*DataMatcher, c *content) *DataMatcher {
+	if len(rules) < 2 {
+		if len(rules) == 0 {
+			return nil
+		}
+		return rules[0]
+	}
+	longest := 0
+	for _, m := range rules {
+		longest = max(longest, m.matchLength(c))
+	}
+	var first *DataMatcher
+	for _, m := range rules {
+		if m.matchLength(c) < longest {
+			continue
+		}
+		if first == nil {
+			first = m
+		}
+		if !slices.ContainsFunc(rules, func(other *DataMatcher) bool {
+			return other.matchLength(c) == longest && !IsA(m.Result.MIME, other.Result.MIME)
+		}) {
+			return m
+		}
+	}
+	return first
+}
+
+// matchLength returns the number of bytes compared by the longest chain of submatches which matches the content, or
+// zero if none does.
+func (m *DataMatcher) matchLength(c *content) int {
+	longest := 0
+	for i := range m.Submatches {
+		longest = max(longest, m.Submatches[i].matchLength(c))
+	}
+	return longest
+}
+
+// matchContent reports whether any of the submatches match the content.
+func (m *DataMatcher) matchContent(c *content) bool {
+	for i := range m.Submatches {
+		if m.Submatches[i].match(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBuffered is like matchContent, but reads the content of b as far as each submatch looks before evaluating it,
+// so that the children of a submatch are only read for if it matches.
+func (m *DataMatcher) matchBuffered(b *bufferedReader) bool {
+	for i := range m.Submatches {
+		if m.Submatches[i].matchBuffered(b) {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *DataMatcher) MatchBytes(b *bufferedReader) bool {
 	for _, match := range m.Submatches {
-		lastOffset := match.Offsets[len(match.Offsets)-1]
-		b.MaybeBuffer(lastOffset + len(match.Bytes))
-		if match.Match(b.Data()) {
+		head, _ := match.extent()
+		b.MaybeBuffer(head)
+		c := b.content()
+		if match.match(&c) {
 			return true
 		}
 	}
 	return false
 }
 
+// Match reports whether the submatch, and one of its children if it has any, match data. Offsets relative to the
+// end of the content are relative to the end of data.
 func (m *DataSubMatcher) Match(data []byte) bool {
-	var peek []byte
-	for _, offset := range m.Offsets {
-		if len(data) <= offset {
-			return false
-		}
-		peek = data[offset:]
-		if len(peek) < len(m.Bytes) {
-			return false
-		}
-		peek = peek[:len(m.Bytes)]
-		if len(m.Mask) > 0 {
-			peek = applyMask(peek, m.Mask)
+	return m.match(&content{head: data, tail: data})
+}
+
+func (m *DataSubMatcher) match(c *content) bool {
+	if !m.matchAny(c) {
+		return false
+	}
+	if len(m.Children) == 0 {
+		return true
+	}
+	for i := range m.Children {
+		if m.Children[i].match(c) {
+			return true
 		}
-		if bytes.Equal(peek, m.Bytes) {
+	}
+	return false
+}
 
-			if len(m.Children) == 0 {
-				return true
-			}
+func (m *DataSubMatcher) matchBuffered(b *bufferedReader) bool {
+	if m.Offset >= 0 {
+		b.readAhead(m.Offset + m.Range + len(m.Bytes))
+	}
+	c := b.available()
+	if !m.matchAny(&c) {
+		return false
+	}
+	if len(m.Children) == 0 {
+		return true
+	}
+	for i := range m.Children {
+		if m.Children[i].matchBuffered(b) {
+			return true
+		}
+	}
+	return false
+}
 
-			for _, child := range m.Children {
-				if child.Match(data) {
-					return true
-				}
-			}
+// matchLength returns the number of bytes compared by the longest chain of submatches, starting with this one, which
+// matches the content, or zero if none does.
+func (m *DataSubMatcher) matchLength(c *content) int {
+	if !m.matchAny(c) {
+		return 0
+	}
+	if len(m.Children) == 0 {
+		return len(m.Bytes)
+	}
+	longest := 0
+	for i := range m.Children {
+		longest = max(longest, m.Children[i].matchLength(c))
+	}
+	if longest == 0 {
+		return 0
+	}
+	return len(m.Bytes) + longest
+}
 
-			continue
+// matchAny reports whether the bytes are found anywhere in the range of offsets, ignoring children.
+func (m *DataSubMatcher) matchAny(c *content) bool {
+	data, start := c.locate(m.Offset)
+	if start < 0 || len(data) < start+len(m.Bytes) {
+		return false
+	}
+	if m.Range == 0 {
+		return m.matchAt(data, start)
+	}
+	end := min(start+m.Range+len(m.Bytes), len(data))
+	if len(m.Mask) == 0 {
+		return bytes.Contains(data[start:end], m.Bytes)
+	}
+	for offset := start; offset+len(m.Bytes) <= end; offset++ {
+		if m.matchAt(data, offset) {
+			return true
 		}
 	}
 	return false
 }
 
-func applyMask(data []byte, mask []byte) []byte {
-	copied := make([]byte, len(data))
-	copy(copied, data)
-	for i := range mask {
-		if i >= len(copied) {
-			break
+// matchAt compares the bytes at the provided offset, which must leave room for all of them in data.
+func (m *DataSubMatcher) matchAt(data []byte, offset int) bool {
+	peek := data[offset : offset+len(m.Bytes)]
+	if len(m.Mask) == 0 {
+		return bytes.Equal(peek, m.Bytes)
+	}
+	for i, b := range peek {
+		if i < len(m.Mask) {
+			b &= m.Mask[i]
+		}
+		if b != m.Bytes[i] {
+			return false
 		}
-		copied[i] &= mask[i]
 	}
-	return copied
+	return true
+}
+
+// extent returns the number of bytes needed from the start and from the end of the content to evaluate the
+// submatch and all of its children.
+func (m *DataSubMatcher) extent() (head, tail int) {
+	if m.Offset >= 0 {
+		head = m.Offset + m.Range + len(m.Bytes)
+	} else {
+		tail = -m.Offset
+	}
+	for i := range m.Children {
+		childHead, childTail := m.Children[i].extent()
+		head, tail = max(head, childHead), max(tail, childTail)
+	}
+	return head, tail
 }
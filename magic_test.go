@@ -2,6 +2,10 @@ package magic
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"gotest.tools/assert"
@@ -224,6 +228,119 @@ func TestIdentifyWithFilename(t *testing.T) {
 
 }
 
+func TestIdentifyWithFilenameArbitration(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"
+	svg := `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`
+
+	tests := []struct {
+		name         string
+		data         string
+		filename     string
+		expectedMIME string
+		legacyMIME   string
+	}{
+		{
+			name:         "binary content is not text",
+			data:         png,
+			filename:     "notes.txt",
+			expectedMIME: "image/png",
+			legacyMIME:   "text/plain",
+		},
+		{
+			name:         "unknown binary content is not text",
+			data:         "\x00\x01\x02\x03\x00\x01\x02\x03",
+			filename:     "notes.txt",
+			expectedMIME: "application/octet-stream",
+			legacyMIME:   "text/plain",
+		},
+		{
+			name:         "unknown binary content and no pattern",
+			data:         "\x00\x01\x02\x03\x00\x01\x02\x03",
+			filename:     "notes",
+			expectedMIME: "application/octet-stream",
+			legacyMIME:   "application/octet-stream",
+		},
+		{
+			name:         "content more specific than the filename",
+			data:         svg,
+			filename:     "drawing.xml",
+			expectedMIME: "image/svg+xml",
+			legacyMIME:   "application/xml",
+		},
+		{
+			name:         "script named as text",
+			data:         "#!/bin/sh\necho hello\n",
+			filename:     "notes.txt",
+			expectedMIME: "application/x-shellscript",
+			legacyMIME:   "text/plain",
+		},
+		{
+			name:         "filename more specific than the content",
+			data:         "PK\x03\x04\x14\x00\x00\x00\x08\x00",
+			filename:     "letter.docx",
+			expectedMIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			legacyMIME:   "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		},
+		{
+			name:         "weak magic does not override the filename",
+			data:         "%PDF-1.7\n",
+			filename:     "notes.txt",
+			expectedMIME: "text/plain",
+			legacyMIME:   "text/plain",
+		},
+		{
+			name:         "no magic",
+			data:         "print(1)\n",
+			filename:     "main.py",
+			expectedMIME: "text/x-python",
+			legacyMIME:   "text/x-python",
+		},
+		{
+			name:         "binary content and several text types",
+			data:         "PK\x03\x04\x14\x00\x00\x00\x08\x00",
+			filename:     "Makefile",
+			expectedMIME: "application/zip",
+			legacyMIME:   "application/zip",
+		},
+	}
+
+	legacy := NewDetector(DefaultRules(), WithLegacyFilenameMatching())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileType := IdentifyWithFilename(bytes.NewBufferString(test.data), test.filename)
+			assert.Equal(t, fileType.MIME, test.expectedMIME)
+			fileType = legacy.IdentifyWithFilename(bytes.NewBufferString(test.data), test.filename)
+			assert.Equal(t, fileType.MIME, test.legacyMIME)
+		})
+	}
+}
+
+func TestIdentifyWithFilenameStrongMagic(t *testing.T) {
+	d := NewDetector(RuleSet{
+		DataMatchers: []DataMatcher{
+			{
+				Submatches: []DataSubMatcher{{Bytes: []byte("STRONG")}},
+				Result:     FileType{MIME: "application/x-strong"},
+				Priority:   80,
+			},
+			{
+				Submatches: []DataSubMatcher{{Bytes: []byte("WEAK")}},
+				Result:     FileType{MIME: "application/x-weak"},
+				Priority:   79,
+			},
+		},
+		FilenameMatchers: []FilenameMatcher{
+			{
+				Pattern:  "*.dat",
+				Result:   FileType{MIME: "application/x-data"},
+				Priority: 50,
+			},
+		},
+	})
+	assert.Equal(t, d.IdentifyWithFilename(bytes.NewBufferString("STRONG"), "file.dat").MIME, "application/x-strong")
+	assert.Equal(t, d.IdentifyWithFilename(bytes.NewBufferString("WEAK"), "file.dat").MIME, "application/x-data")
+}
+
 func TestIdentifyBytes(t *testing.T) {
 
 	tests := []struct {
@@ -241,6 +358,11 @@ func TestIdentifyBytes(t *testing.T) {
 			expectedMIME: "application/octet-stream",
 			detail:       "non-specific binary file type",
 		},
+		{
+			data:         []byte("\x00\x01\x02\x03"),
+			expectedMIME: "application/octet-stream",
+			detail:       "control characters which are valid UTF-8",
+		},
 		{
 			data:         []byte("ID3"),
 			expectedMIME: "audio/mpeg",
@@ -480,3 +602,225 @@ func TestIdentifyBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestIdentifyWholeBytesMatchesIdentify(t *testing.T) {
+	for _, data := range benchmarkSamples {
+		assert.Equal(t, IdentifyWholeBytes(data), Identify(bytes.NewReader(data)))
+	}
+}
+
+func TestIdentifyBytesDoesNotAllocate(t *testing.T) {
+	for _, data := range benchmarkSamples {
+		if IsA(IdentifyBytes(data).MIME, "application/xml") {
+			// the root element of XML documents is parsed
+			continue
+		}
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = IdentifyBytes(data)
+		})
+		assert.Equal(t, allocs, float64(0), "%s", IdentifyBytes(data).MIME)
+	}
+}
+
+func TestIdentifyBytesMaxRead(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0xff}, 64), "conectix"...)
+	data = append(data, make([]byte, 504)...)
+	assert.Equal(t, IdentifyWholeBytes(data).MIME, "application/x-vhd-disk")
+
+	// the footer is beyond the read limit, so the end of the content is unknown
+	d := NewDetector(DefaultRules(), WithMaxRead(512))
+	assert.Equal(t, d.IdentifyWholeBytes(data).MIME, "application/octet-stream")
+	assert.Equal(t, d.IdentifyWholeBytes([]byte("%PDF-1.7\n")).MIME, "application/pdf")
+}
+
+func TestIdentifyPrefersMostSpecific(t *testing.T) {
+	rule := func(mime, data string) DataMatcher {
+		return DataMatcher{
+			Submatches: []DataSubMatcher{{Bytes: []byte(data)}},
+			Result:     FileType{MIME: mime},
+			Priority:   50,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		rules        []DataMatcher
+		expectedMIME string
+		// orderIndependent is set if the result does not depend on the order of the rules
+		orderIndependent bool
+	}{
+		{
+			name:             "longest match",
+			rules:            []DataMatcher{rule("application/x-short", "Og"), rule("application/x-long", "OggS")},
+			expectedMIME:     "application/x-long",
+			orderIndependent: true,
+		},
+		{
+			name:             "sub-class",
+			rules:            []DataMatcher{rule("application/ogg", "OggS"), rule("audio/ogg", "OggS")},
+			expectedMIME:     "audio/ogg",
+			orderIndependent: true,
+		},
+		{
+			name:             "longest match before sub-class",
+			rules:            []DataMatcher{rule("application/ogg", "OggS"), rule("audio/ogg", "Ogg")},
+			expectedMIME:     "application/ogg",
+			orderIndependent: true,
+		},
+		{
+			// neither is a sub-class of the other, so the rule which comes first is used
+			name: "sibling sub-classes",
+			rules: []DataMatcher{
+				rule("video/ogg", "OggS"), rule("application/ogg", "OggS"), rule("audio/ogg", "OggS"),
+			},
+			expectedMIME: "video/ogg",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDetector(RuleSet{DataMatchers: test.rules})
+			assert.Equal(t, d.Identify(bytes.NewBufferString("OggS")).MIME, test.expectedMIME)
+			if test.orderIndependent {
+				slices.Reverse(test.rules)
+				d = NewDetector(RuleSet{DataMatchers: test.rules})
+				assert.Equal(t, d.Identify(bytes.NewBufferString("OggS")).MIME, test.expectedMIME)
+			}
+		})
+	}
+}
+
+func TestIdentifyBytesIsPrefix(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0xff}, 64), "conectix"...)
+	data = append(data, make([]byte, 504)...)
+	assert.Equal(t, IdentifyWholeBytes(data).MIME, "application/x-vhd-disk")
+
+	// the end of the first few kilobytes is not the end of the content, so footers are not looked for
+	assert.Equal(t, IdentifyBytes(data).MIME, "application/octet-stream")
+	assert.Equal(t, IdentifyBytes([]byte("%PDF-1.7\n")).MIME, "application/pdf")
+	assert.Equal(t, IdentifyBytesWithFilename(data, "disk.vhd").MIME, "application/x-vhd-disk")
+	assert.Equal(t, IdentifyWholeBytesWithFilename(data, "disk").MIME, "application/x-vhd-disk")
+
+	id3 := bytes.Repeat([]byte{0xff}, 4096)
+	copy(id3[len(id3)-128:], "TAG")
+	assert.Equal(t, IdentifyWholeBytes(id3).MIME, "audio/mpeg")
+	assert.Equal(t, IdentifyBytes(id3).MIME, "application/octet-stream")
+}
+
+func TestIdentifyAcronym(t *testing.T) {
+	fileType := Identify(bytes.NewBuffer([]byte("%PDF-1.")))
+	assert.Equal(t, fileType.Acronym, "PDF")
+	assert.Equal(t, fileType.ExpandedAcronym, "Portable Document Format")
+}
+
+var benchmarkSamples = [][]byte{
+	[]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"),
+	[]byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"),
+	[]byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"),
+	[]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"),
+	[]byte("<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+	[]byte("this is a non-specific file type"),
+	[]byte("\x99\x99\x99\x99\x99\x99\x99\x99"),
+}
+
+func BenchmarkIdentify(b *testing.B) {
+	r := bytes.NewReader(nil)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		r.Reset(benchmarkSamples[i%len(benchmarkSamples)])
+		_ = Identify(r)
+	}
+}
+
+func BenchmarkIdentifyBytes(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		_ = IdentifyBytes(benchmarkSamples[i%len(benchmarkSamples)])
+	}
+}
+
+// countingReaderAt counts the bytes read from a ReaderAt.
+type countingReaderAt struct {
+	r    io.ReaderAt
+	read int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.read += n
+	return n, err
+}
+
+func TestIdentifyReaderAt(t *testing.T) {
+	footer := append([]byte("conectix"), make([]byte, 504)...)
+	vhd := append(bytes.Repeat([]byte{0xff}, 1<<20), footer...)
+
+	r := &countingReaderAt{r: bytes.NewReader(vhd)}
+	assert.Equal(t, IdentifyReaderAt(r, int64(len(vhd))).MIME, "application/x-vhd-disk")
+	assert.Assert(t, r.read < 64<<10, "read %d bytes", r.read)
+
+	// a stream can only be identified by its footer if it is short enough to be read in full
+	assert.Assert(t, Identify(bytes.NewReader(vhd)).MIME != "application/x-vhd-disk")
+	small := append(bytes.Repeat([]byte{0xff}, 512), footer...)
+	assert.Equal(t, Identify(bytes.NewReader(small)).MIME, "application/x-vhd-disk")
+
+	// the footer is beyond the read limit
+	d := NewDetector(DefaultRules(), WithMaxRead(512))
+	assert.Assert(t, d.IdentifyReaderAt(bytes.NewReader(vhd), int64(len(vhd))).MIME != "application/x-vhd-disk")
+
+	assert.Equal(t, IdentifyReaderAt(bytes.NewReader([]byte("%PDF-1.7\n")), 9).MIME, "application/pdf")
+	assert.Equal(t, IdentifyReaderAt(bytes.NewReader(nil), 0).MIME, "text/plain")
+}
+
+func TestIdentifyPathReadsEnd(t *testing.T) {
+	tag := append([]byte("TAG"), bytes.Repeat([]byte{0xff}, 125)...)
+	content := append(bytes.Repeat([]byte{0xff}, 1<<20), tag...)
+	path := filepath.Join(t.TempDir(), "track")
+	assert.NilError(t, os.WriteFile(path, content, 0o600))
+
+	ft, err := IdentifyPath(path)
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "audio/mpeg")
+}
+
+func TestIdentifyPathEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	assert.NilError(t, os.WriteFile(path, nil, 0o600))
+
+	ft, err := IdentifyPath(path)
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "application/x-zerosize")
+}
+
+func TestIdentifyPathSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "image.png")
+	assert.NilError(t, os.WriteFile(target, []byte("\x89PNG\r\n\x1a\n"), 0o600))
+	if err := os.Symlink(target, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symbolic links are not supported: %s", err)
+	}
+	assert.NilError(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "broken")))
+	assert.NilError(t, os.Symlink(dir, filepath.Join(dir, "parent")))
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		path         string
+		expectedMIME string
+	}{
+		{name: "followed", path: "link", expectedMIME: "image/png"},
+		{name: "followed to a directory", path: "parent", expectedMIME: "inode/directory"},
+		{name: "broken", path: "broken", expectedMIME: "inode/symlink"},
+		{name: "not followed", opts: []Option{WithoutFollowingSymlinks()}, path: "link", expectedMIME: "inode/symlink"},
+		{name: "not followed to a directory", opts: []Option{WithoutFollowingSymlinks()}, path: "parent", expectedMIME: "inode/symlink"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDetector(DefaultRules(), test.opts...)
+			ft, err := d.IdentifyPath(filepath.Join(dir, test.path))
+			assert.NilError(t, err)
+			assert.Equal(t, ft.MIME, test.expectedMIME)
+		})
+	}
+}
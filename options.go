@@ -0,0 +1,59 @@
+package magic
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrBudgetExhausted is returned when identification is cut short by one of the limits set with WithMaxRead,
+// WithMaxBuffer or WithTimeout.
+var ErrBudgetExhausted = errors.New("identification budget exhausted")
+
+// Option configures a Detector.
+type Option func(*Detector)
+
+// WithMaxRead limits the number of bytes a Detector reads from the content it identifies. Rules which need to look
+// further into the content than this cannot match. By default, as much content is read as the rules need.
+func WithMaxRead(n int) Option {
+	return func(d *Detector) {
+		d.maxRead = n
+	}
+}
+
+// WithMaxBuffer limits the number of bytes a Detector holds in memory while identifying content, and so the memory
+// used by each identification. Content read from a reader is buffered as it is read, so the smaller of this and
+// WithMaxRead applies to it, whereas content given to IdentifyBytes is used in place, so only WithMaxRead limits
+// how much of it is inspected.
+func WithMaxBuffer(n int) Option {
+	return func(d *Detector) {
+		d.maxBuffer = n
+	}
+}
+
+// WithTimeout limits the time a Detector spends reading the content it identifies, after which it identifies
+// whatever it has read so far. Reads which are still in progress are abandoned, so a reader which has timed out
+// should not be used again.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Detector) {
+		d.timeout = timeout
+	}
+}
+
+// WithLegacyFilenameMatching makes IdentifyWithFilename, and the functions built on it, weigh the filename against
+// the content as earlier versions did: the content is only used if no pattern matches the filename, or if patterns
+// for several types do, in which case the first of them is used if no magic rule matches the content. Files are
+// then identified without reading their content whenever their name is enough, but content which contradicts the
+// filename, such as a PNG image named like a text file, is identified by its name.
+func WithLegacyFilenameMatching() Option {
+	return func(d *Detector) {
+		d.legacyFilenameMatching = true
+	}
+}
+
+// WithoutFollowingSymlinks makes IdentifyPath identify symbolic links as inode/symlink, rather than identifying the
+// file they point to.
+func WithoutFollowingSymlinks() Option {
+	return func(d *Detector) {
+		d.noFollowSymlinks = true
+	}
+}
@@ -0,0 +1,221 @@
+package magic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var farRules = RuleSet{
+	DataMatchers: []DataMatcher{
+		{
+			Submatches: []DataSubMatcher{{Bytes: []byte("NEAR"), Offset: 0}},
+			Result:     FileType{MIME: "application/x-near"},
+			Priority:   50,
+		},
+		{
+			Submatches: []DataSubMatcher{{Bytes: []byte("FAR"), Offset: 1024}},
+			Result:     FileType{MIME: "application/x-far"},
+			Priority:   50,
+		},
+	},
+}
+
+func TestReadBudget(t *testing.T) {
+	far := append(bytes.Repeat([]byte{0xff}, 1024), "FAR"...)
+	near := append([]byte("NEAR"), far[4:]...)
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		content      []byte
+		expectedMIME string
+		expectedErr  error
+	}{
+		{
+			name:         "no limit",
+			content:      far,
+			expectedMIME: "application/x-far",
+		},
+		{
+			name:         "max read",
+			opts:         []Option{WithMaxRead(512)},
+			content:      far,
+			expectedMIME: "application/octet-stream",
+			expectedErr:  ErrBudgetExhausted,
+		},
+		{
+			name:         "max buffer",
+			opts:         []Option{WithMaxBuffer(512)},
+			content:      far,
+			expectedMIME: "application/octet-stream",
+			expectedErr:  ErrBudgetExhausted,
+		},
+		{
+			name:         "smallest limit applies",
+			opts:         []Option{WithMaxRead(2048), WithMaxBuffer(512)},
+			content:      far,
+			expectedMIME: "application/octet-stream",
+			expectedErr:  ErrBudgetExhausted,
+		},
+		{
+			name:         "match within limit",
+			opts:         []Option{WithMaxRead(512)},
+			content:      near,
+			expectedMIME: "application/x-near",
+		},
+		{
+			name:         "content within limit",
+			opts:         []Option{WithMaxRead(4096)},
+			content:      []byte{0xff, 0xff},
+			expectedMIME: "application/octet-stream",
+			expectedErr:  ErrUnknownType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDetector(farRules, test.opts...)
+
+			ft, err := d.IdentifyContext(context.Background(), bytes.NewReader(test.content))
+			if test.expectedErr == nil {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, errors.Is(err, test.expectedErr), "got %v", err)
+			}
+			assert.Equal(t, ft.MIME, test.expectedMIME)
+
+			// every entry point applies the same limits
+			assert.Equal(t, d.Identify(bytes.NewReader(test.content)).MIME, test.expectedMIME)
+		})
+	}
+}
+
+func TestReadBudgetLimitsBuffer(t *testing.T) {
+	d := NewDetector(farRules, WithMaxBuffer(512))
+	b, done := d.buffer(context.Background(), bytes.NewReader(make([]byte, 4096)))
+	defer done()
+	d.rules.Load().identify(b, nil)
+	assert.Assert(t, cap(b.Data()) <= 512)
+}
+
+func TestMaxBufferDoesNotLimitBytes(t *testing.T) {
+	far := append(bytes.Repeat([]byte{0xff}, 1024), "FAR"...)
+
+	// the bytes are used in place, so only the read limit stops them being inspected
+	d := NewDetector(farRules, WithMaxBuffer(512))
+	assert.Equal(t, d.IdentifyBytes(far).MIME, "application/x-far")
+	assert.Equal(t, d.Identify(bytes.NewReader(far)).MIME, "application/octet-stream")
+
+	d = NewDetector(farRules, WithMaxRead(512))
+	assert.Equal(t, d.IdentifyBytes(far).MIME, "application/octet-stream")
+}
+
+func TestBudgetExhaustedByEveryEntryPoint(t *testing.T) {
+	far := append(bytes.Repeat([]byte{0xff}, 1024), "FAR"...)
+	rules := farRules
+	rules.TreeMatchers = []TreeMatcher{{
+		Submatches: []TreeSubMatcher{{Path: "far.bin", MIMEType: "application/x-far"}},
+		Result:     FileType{MIME: "x-content/far"},
+		Priority:   50,
+	}}
+	d := NewDetector(rules, WithMaxRead(512))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "far.bin")
+	assert.NilError(t, os.WriteFile(path, far, 0o600))
+
+	tests := []struct {
+		name     string
+		identify func() error
+	}{
+		{
+			name: "Sniff",
+			identify: func() error {
+				_, _, err := d.Sniff(bytes.NewReader(far))
+				return err
+			},
+		},
+		{
+			name: "SniffReadSeeker",
+			identify: func() error {
+				_, err := d.SniffReadSeeker(bytes.NewReader(far))
+				return err
+			},
+		},
+		{
+			name: "Verify",
+			identify: func() error {
+				_, err := d.Verify(bytes.NewReader(far), "", "application/x-far")
+				return err
+			},
+		},
+		{
+			name: "IdentifyPath",
+			identify: func() error {
+				_, err := d.IdentifyPath(path)
+				return err
+			},
+		},
+		{
+			name: "IdentifyDir",
+			identify: func() error {
+				_, err := d.IdentifyDir(dir)
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.identify()
+			assert.Assert(t, errors.Is(err, ErrBudgetExhausted), "got %v", err)
+		})
+	}
+}
+
+func TestLargeOffsetAllocatesLittle(t *testing.T) {
+	d := NewDetector(RuleSet{DataMatchers: []DataMatcher{{
+		Submatches: []DataSubMatcher{{Bytes: []byte("FAR"), Offset: 1 << 28}},
+		Result:     FileType{MIME: "application/x-far"},
+		Priority:   50,
+	}}})
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	ft := d.Identify(bytes.NewReader([]byte("hello")))
+	runtime.ReadMemStats(&after)
+	assert.Equal(t, ft.MIME, "text/plain")
+	assert.Assert(t, after.TotalAlloc-before.TotalAlloc < 64<<10, "allocated %d bytes", after.TotalAlloc-before.TotalAlloc)
+}
+
+func TestTimeout(t *testing.T) {
+	d := NewDetector(testRules, WithTimeout(10*time.Millisecond))
+
+	pr, pw := io.Pipe()
+	defer func() { _ = pw.Close() }()
+	go func() {
+		_, _ = pw.Write([]byte("TE"))
+	}()
+
+	// the rest of the content never arrives, so the time limit is reached
+	ft, err := d.IdentifyContext(context.Background(), pr)
+	assert.Assert(t, errors.Is(err, ErrBudgetExhausted), "got %v", err)
+	assert.Equal(t, ft.MIME, "text/plain")
+
+	pr, pw = io.Pipe()
+	defer func() { _ = pw.Close() }()
+	assert.Equal(t, d.Identify(pr).MIME, "text/plain")
+
+	ft, err = d.IdentifyContext(context.Background(), bytes.NewReader([]byte("TEST")))
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "application/x-test")
+}
@@ -0,0 +1,67 @@
+package magic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+)
+
+// Sniff looks up the file type of a stream and returns a reader which replays it. See Detector.Sniff.
+func Sniff(r io.Reader) (FileType, io.Reader, error) {
+	return defaultDetector.Sniff(r)
+}
+
+// Sniff looks up the file type based on the bytes read from r, like Identify, and returns a reader which replays
+// those bytes followed by the rest of r. This allows a stream which cannot be rewound, such as an HTTP request
+// body, to be identified and then used as if it had never been read. If r could not be read, the error is returned
+// and the returned reader replays whatever was read before returning the same error. If a limit was reached before
+// any rule matched, ErrBudgetExhausted is returned along with a reader which replays the whole stream.
+func (d *Detector) Sniff(r io.Reader) (FileType, io.Reader, error) {
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+	ft := d.rules.Load().identify(b, nil)
+
+	replay := bytes.NewReader(b.Data())
+	switch {
+	case b.err == nil:
+		return ft, io.MultiReader(replay, r), contentErr(b, ft)
+	case errors.Is(b.err, io.EOF):
+		return ft, replay, nil
+	default:
+		// a read may have been abandoned part way through, so r cannot be used again
+		return ft, io.MultiReader(replay, errReader{err: b.err}), b.err
+	}
+}
+
+// SniffReadSeeker looks up the file type of rs and seeks back to where it started. See Detector.SniffReadSeeker.
+func SniffReadSeeker(rs io.ReadSeeker) (FileType, error) {
+	return defaultDetector.SniffReadSeeker(rs)
+}
+
+// SniffReadSeeker looks up the file type based on the bytes read from rs, like Identify, and then seeks back to
+// where reading started, so that rs can be used as if it had never been read. Errors are returned like Sniff.
+func (d *Detector) SniffReadSeeker(rs io.ReadSeeker) (FileType, error) {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return unknownBinaryFileType, err
+	}
+
+	b, done := d.buffer(context.Background(), rs)
+	ft := d.rules.Load().identify(b, nil)
+	done()
+
+	if _, err := rs.Seek(start, io.SeekStart); err != nil {
+		return ft, err
+	}
+	return ft, contentErr(b, ft)
+}
+
+// errReader returns the same error from every read.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
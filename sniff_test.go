@@ -0,0 +1,83 @@
+package magic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"gotest.tools/assert"
+)
+
+func TestSniff(t *testing.T) {
+	content := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte("0123456789"), 4096)...)
+
+	tests := []struct {
+		name     string
+		reader   io.Reader
+		expected []byte
+	}{
+		{
+			name:     "reader",
+			reader:   bytes.NewReader(content),
+			expected: content,
+		},
+		{
+			name:     "one byte at a time",
+			reader:   iotest.OneByteReader(bytes.NewReader(content)),
+			expected: content,
+		},
+		{
+			name:     "shorter than buffered",
+			reader:   bytes.NewReader(content[:16]),
+			expected: content[:16],
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ft, r, err := Sniff(test.reader)
+			assert.NilError(t, err)
+			assert.Equal(t, ft.MIME, "image/png")
+
+			replayed, err := io.ReadAll(r)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, replayed, test.expected)
+		})
+	}
+}
+
+func TestSniffReadError(t *testing.T) {
+	failure := errors.New("connection reset")
+	ft, r, err := Sniff(io.MultiReader(bytes.NewReader([]byte("%PDF-1.7\n")), iotest.ErrReader(failure)))
+	assert.Assert(t, errors.Is(err, failure), "got %v", err)
+	assert.Equal(t, ft.MIME, "application/pdf")
+
+	replayed, err := io.ReadAll(r)
+	assert.Assert(t, errors.Is(err, failure), "got %v", err)
+	assert.Equal(t, string(replayed), "%PDF-1.7\n")
+}
+
+func TestSniffReadSeeker(t *testing.T) {
+	content := []byte("header%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	path := filepath.Join(t.TempDir(), "document")
+	assert.NilError(t, os.WriteFile(path, content, 0o600))
+
+	f, err := os.Open(path)
+	assert.NilError(t, err)
+	defer func() { _ = f.Close() }()
+
+	_, err = f.Seek(int64(len("header")), io.SeekStart)
+	assert.NilError(t, err)
+
+	ft, err := SniffReadSeeker(f)
+	assert.NilError(t, err)
+	assert.Equal(t, ft.MIME, "application/pdf")
+
+	rest, err := io.ReadAll(f)
+	assert.NilError(t, err)
+	assert.Equal(t, string(rest), string(content[len("header"):]))
+}
@@ -0,0 +1,203 @@
+package magic
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+var directoryFileType = FileType{
+	Description: "Folder",
+	MIME:        "inode/directory",
+	Icon:        "folder",
+}
+
+// TreeMatcher identifies a directory, such as the root of a mounted volume, by the files and directories it
+// contains. These are the freedesktop "treemagic" rules.
+type TreeMatcher struct {
+	Submatches []TreeSubMatcher
+	Result     FileType
+	Priority   int
+}
+
+// TreeSubMatcher matches a single path within a directory. Children are only evaluated if the path matches, and
+// at least one of them must also match.
+type TreeSubMatcher struct {
+	// Path is the slash-separated path to match, relative to the directory being identified.
+	Path string
+	// Type is the required type of the path: "file", "directory" or "link". Any type matches if empty.
+	Type string
+	// MatchCase requires Path to match case-sensitively.
+	MatchCase bool
+	// Executable requires the path to be executable.
+	Executable bool
+	// NonEmpty requires a directory to contain at least one entry, or a file to contain at least one byte.
+	NonEmpty bool
+	// MIMEType requires the content of the path to be of (or a sub-class of) this MIME type.
+	MIMEType string
+	Children []TreeSubMatcher
+}
+
+// IdentifyDir looks up the type of the directory at the provided path. See Detector.IdentifyDir.
+func IdentifyDir(path string) (FileType, error) {
+	return defaultDetector.IdentifyDir(path)
+}
+
+// IdentifyDir looks up the type of the directory at the provided path, such as a DVD or a camera memory card,
+// based on its contents. Directories which match no rules are identified as inode/directory.
+func (d *Detector) IdentifyDir(path string) (FileType, error) {
+	return d.IdentifyFS(os.DirFS(path))
+}
+
+// IdentifyFS looks up the type of the root of the provided file system. See Detector.IdentifyFS.
+func IdentifyFS(fsys fs.FS) (FileType, error) {
+	return defaultDetector.IdentifyFS(fsys)
+}
+
+// IdentifyFS looks up the type of the root of the provided file system based on its contents. File systems
+// which match no rules are identified as inode/directory.
+func (d *Detector) IdentifyFS(fsys fs.FS) (FileType, error) {
+	if _, err := fs.ReadDir(fsys, "."); err != nil {
+		return unknownBinaryFileType, err
+	}
+	rules := d.rules.Load()
+	// a file which could not be identified may have kept a rule from matching, which is reported if none does
+	var err error
+	identify := func(r io.Reader, filename string) FileType {
+		b, done := d.buffer(context.Background(), r)
+		defer done()
+		ft := d.identifyWithFilename(rules, b, filename, nil)
+		if err == nil {
+			err = contentErr(b, ft)
+		}
+		return ft
+	}
+	for _, t := range rules.treeMatchers {
+		if t.match(fsys, identify) {
+			return t.Result, nil
+		}
+	}
+	return directoryFileType, err
+}
+
+// match reports whether the file system matches the rule, using identify to look up the type of any files the rule
+// requires to be of a particular type.
+func (m *TreeMatcher) match(fsys fs.FS, identify func(r io.Reader, filename string) FileType) bool {
+	for _, sub := range m.Submatches {
+		if sub.match(fsys, identify) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *TreeSubMatcher) match(fsys fs.FS, identify func(r io.Reader, filename string) FileType) bool {
+	name, entry, ok := lookupTreePath(fsys, m.Path, m.MatchCase)
+	if !ok {
+		return false
+	}
+
+	if m.Type == "link" && entry.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+
+	// apart from the link check above, symbolic links are followed
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return false
+	}
+
+	switch m.Type {
+	case "file":
+		if !info.Mode().IsRegular() {
+			return false
+		}
+	case "directory":
+		if !info.IsDir() {
+			return false
+		}
+	}
+
+	if m.Executable && info.Mode().Perm()&0o111 == 0 {
+		return false
+	}
+
+	if m.NonEmpty {
+		if info.IsDir() {
+			entries, err := fs.ReadDir(fsys, name)
+			if err != nil || len(entries) == 0 {
+				return false
+			}
+		} else if info.Size() == 0 {
+			return false
+		}
+	}
+
+	if m.MIMEType != "" {
+		// only regular files are read, as reading a pipe or a device could block or have side effects
+		if !info.Mode().IsRegular() {
+			return false
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return false
+		}
+		ft := identify(f, path.Base(name))
+		_ = f.Close()
+		if !IsA(ft.MIME, m.MIMEType) {
+			return false
+		}
+	}
+
+	if len(m.Children) == 0 {
+		return true
+	}
+	for _, child := range m.Children {
+		if child.match(fsys, identify) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTreePath finds the entry for the provided slash-separated path, matching each element
+// case-insensitively unless matchCase is set. It returns the path as it exists in fsys.
+func lookupTreePath(fsys fs.FS, target string, matchCase bool) (string, fs.DirEntry, bool) {
+	dir := "."
+	var entry fs.DirEntry
+	for _, element := range strings.Split(strings.Trim(target, "/"), "/") {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return "", nil, false
+		}
+		entry = findDirEntry(entries, element, matchCase)
+		if entry == nil {
+			return "", nil, false
+		}
+		dir = path.Join(dir, entry.Name())
+	}
+	if entry == nil {
+		return "", nil, false
+	}
+	return dir, entry, true
+}
+
+func findDirEntry(entries []fs.DirEntry, name string, matchCase bool) fs.DirEntry {
+	for _, e := range entries {
+		if e.Name() == name {
+			return e
+		}
+	}
+	if matchCase {
+		return nil
+	}
+	for _, e := range entries {
+		if strings.EqualFold(e.Name(), name) {
+			return e
+		}
+	}
+	return nil
+}
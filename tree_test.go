@@ -0,0 +1,105 @@
+package magic
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"gotest.tools/assert"
+)
+
+func TestIdentifyFS(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		expectedMIME string
+	}{
+		{
+			name: "camera memory card",
+			fsys: fstest.MapFS{
+				"DCIM/100CANON/IMG_0001.JPG": {Data: []byte("\xff\xd8\xff")},
+			},
+			expectedMIME: "x-content/image-dcf",
+		},
+		{
+			name: "empty DCIM directory",
+			fsys: fstest.MapFS{
+				"DCIM": {Mode: fs.ModeDir},
+			},
+			expectedMIME: "inode/directory",
+		},
+		{
+			name: "video DVD",
+			fsys: fstest.MapFS{
+				"video_ts/video_ts.ifo": {Data: []byte("DVDVIDEO-VMG")},
+			},
+			expectedMIME: "x-content/video-dvd",
+		},
+		{
+			name: "case-sensitive match",
+			fsys: fstest.MapFS{
+				"AUTORUN.SH": {Data: []byte("#!/bin/sh\n")},
+			},
+			expectedMIME: "inode/directory",
+		},
+		{
+			name: "unix software",
+			fsys: fstest.MapFS{
+				"autorun.sh": {Data: []byte("#!/bin/sh\n")},
+			},
+			expectedMIME: "x-content/unix-software",
+		},
+		{
+			name: "windows software must be executable",
+			fsys: fstest.MapFS{
+				"autorun.exe": {Data: []byte("MZ"), Mode: 0o644},
+			},
+			expectedMIME: "inode/directory",
+		},
+		{
+			name: "windows software",
+			fsys: fstest.MapFS{
+				"autorun.exe": {Data: []byte("MZ"), Mode: 0o755},
+			},
+			expectedMIME: "x-content/win32-software",
+		},
+		{
+			name: "plain directory",
+			fsys: fstest.MapFS{
+				"notes.txt": {Data: []byte("hello")},
+			},
+			expectedMIME: "inode/directory",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileType, err := IdentifyFS(test.fsys)
+			assert.NilError(t, err)
+			assert.Equal(t, fileType.MIME, test.expectedMIME)
+		})
+	}
+}
+
+func TestIdentifyFSMIMETypeRequiresRegularFile(t *testing.T) {
+	d := NewDetector(RuleSet{TreeMatchers: []TreeMatcher{{
+		Submatches: []TreeSubMatcher{{Path: "autorun.txt", MIMEType: "text/plain"}},
+		Result:     FileType{MIME: "x-content/custom"},
+		Priority:   50,
+	}}})
+
+	fileType, err := d.IdentifyFS(fstest.MapFS{"autorun.txt": {Data: []byte("hello")}})
+	assert.NilError(t, err)
+	assert.Equal(t, fileType.MIME, "x-content/custom")
+
+	fileType, err = d.IdentifyFS(fstest.MapFS{"autorun.txt": {Data: []byte("hello"), Mode: fs.ModeNamedPipe}})
+	assert.NilError(t, err)
+	assert.Equal(t, fileType.MIME, "inode/directory")
+}
+
+func TestIdentifyPathDirectory(t *testing.T) {
+	fileType, err := IdentifyPath(t.TempDir())
+	assert.NilError(t, err)
+	assert.Equal(t, fileType.MIME, "inode/directory")
+}
@@ -0,0 +1,140 @@
+package magic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// VerifyResult describes how well some content agrees with the file type it is expected to be.
+type VerifyResult struct {
+	// Detected is the file type identified from the content alone.
+	Detected FileType
+	// Valid is true if the content is one of the expected MIME types.
+	Valid bool
+	// Matched is the expected MIME type which the content satisfied, if Valid is true.
+	Matched string
+	// ExtensionMatches is true if the filename's extension agrees with the content.
+	ExtensionMatches bool
+	// Mismatches explains each way in which the input disagreed with what was expected.
+	Mismatches []string
+}
+
+// Verify checks that the content read from r is one of the expected MIME types. See Detector.Verify.
+func Verify(r io.Reader, filename string, expected ...string) (VerifyResult, error) {
+	return defaultDetector.Verify(r, filename, expected...)
+}
+
+// Verify checks that the content read from r is one of the expected MIME types, and that the extension of the
+// provided filename agrees with the content. The filename may be empty, in which case only the content is
+// checked. As well as the result, it returns any error which stopped the content being read, or ErrBudgetExhausted
+// if the content did not verify within the detector's limits, so that content which could not be read in full is
+// not mistaken for content of the wrong type.
+func (d *Detector) Verify(r io.Reader, filename string, expected ...string) (VerifyResult, error) {
+	if len(expected) == 0 {
+		return VerifyResult{}, errors.New("no expected MIME types provided")
+	}
+
+	rules := d.rules.Load()
+	b, done := d.buffer(context.Background(), r)
+	defer done()
+
+	// every matching magic rule is considered, as well as the sub-class hierarchy, so content which matches
+	// both a format and the more general format it is built on (e.g. a document inside a zip archive) satisfies
+	// either
+	candidates := rules.matchAllData(b)
+
+	result := VerifyResult{
+		Detected: rules.identify(b, nil),
+	}
+
+	for _, mime := range expected {
+		if contentIs(mime, result.Detected, candidates) {
+			result.Valid = true
+			result.Matched = mime
+			break
+		}
+	}
+	if !result.Valid {
+		result.Mismatches = append(result.Mismatches, fmt.Sprintf(
+			"content is %s, not %s", result.Detected.MIME, strings.Join(expected, " or "),
+		))
+	}
+
+	if filename == "" {
+		result.ExtensionMatches = true
+	} else {
+		rules.verifyFilename(&result, filepath.Base(filename), candidates)
+	}
+	return result, verifyErr(b, result)
+}
+
+// verifyFilename checks that the provided filename agrees with the content, recording the outcome in result.
+func (rules *ruleTable) verifyFilename(result *VerifyResult, filename string, candidates []Candidate) {
+	globs := rules.matchFilename(filename)
+	if len(globs) == 0 {
+		result.Mismatches = append(result.Mismatches, fmt.Sprintf("filename %q does not match any known type", filename))
+		return
+	}
+
+	mimes := uniqueMIMEs(globs)
+	for _, mime := range mimes {
+		if rules.extensionAgrees(mime, result.Detected, candidates) {
+			result.ExtensionMatches = true
+			break
+		}
+	}
+	if !result.ExtensionMatches {
+		result.Mismatches = append(result.Mismatches, fmt.Sprintf(
+			"filename %q suggests %s, but content is %s", filename, strings.Join(mimes, " or "), result.Detected.MIME,
+		))
+	}
+}
+
+// verifyErr returns the error, if any, which should accompany the result of verifying the provided content. Like
+// identifyErr, it returns the error which stopped reading, but reports an exhausted budget whenever the content did
+// not verify, as the expected type may have been beyond the limits.
+func verifyErr(b *bufferedReader, result VerifyResult) error {
+	if err := b.readErr(); err != nil {
+		return err
+	}
+	if b.exhausted && !result.Valid {
+		return fmt.Errorf("%w: not verified within the first %d bytes", ErrBudgetExhausted, len(b.buffer))
+	}
+	return nil
+}
+
+// contentIs reports whether content identified as detected, and matching the provided candidates, can be
+// considered to be of the provided MIME type.
+func contentIs(mime string, detected FileType, candidates []Candidate) bool {
+	if IsA(detected.MIME, mime) {
+		return true
+	}
+	for _, c := range candidates {
+		if IsA(c.FileType.MIME, mime) {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionAgrees is like contentIs, but gives the benefit of the doubt when no magic rule matched the content,
+// as many formats have no magic at all. A format which does have magic should have matched, and binary content
+// never agrees with a text format.
+func (rules *ruleTable) extensionAgrees(mime string, detected FileType, candidates []Candidate) bool {
+	if contentIs(mime, detected, candidates) {
+		return true
+	}
+	if len(candidates) > 0 {
+		return false
+	}
+	for _, m := range rules.dataMatchers {
+		if m.Result.MIME == mime {
+			return false
+		}
+	}
+	return detected == unknownTextFileType || !IsA(mime, "text/plain")
+}
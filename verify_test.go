@@ -0,0 +1,137 @@
+package magic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"gotest.tools/assert"
+)
+
+func TestVerify(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		data             []byte
+		filename         string
+		expected         []string
+		valid            bool
+		extensionMatches bool
+	}{
+		{
+			name:             "matching PNG",
+			data:             []byte("\x89PNG\r\n\x1a\n"),
+			filename:         "image.png",
+			expected:         []string{"image/png"},
+			valid:            true,
+			extensionMatches: true,
+		},
+		{
+			name:             "one of several expected types",
+			data:             []byte("\x89PNG\r\n\x1a\n"),
+			filename:         "image.png",
+			expected:         []string{"image/jpeg", "image/png"},
+			valid:            true,
+			extensionMatches: true,
+		},
+		{
+			name:             "PNG with wrong extension",
+			data:             []byte("\x89PNG\r\n\x1a\n"),
+			filename:         "image.jpg",
+			expected:         []string{"image/png"},
+			valid:            true,
+			extensionMatches: false,
+		},
+		{
+			name:             "zip disguised as PDF",
+			data:             []byte("PK\x03\x04"),
+			filename:         "report.pdf",
+			expected:         []string{"application/pdf"},
+			valid:            false,
+			extensionMatches: false,
+		},
+		{
+			name:             "no filename",
+			data:             []byte("%PDF-1."),
+			expected:         []string{"application/pdf"},
+			valid:            true,
+			extensionMatches: true,
+		},
+		{
+			name:             "text without magic",
+			data:             []byte("a,b,c\n1,2,3\n"),
+			filename:         "data.csv",
+			expected:         []string{"text/plain"},
+			valid:            true,
+			extensionMatches: true,
+		},
+		{
+			name:             "binary with text extension",
+			data:             []byte("\x99\x99\x99\x99"),
+			filename:         "notes.txt",
+			expected:         []string{"text/plain"},
+			valid:            false,
+			extensionMatches: false,
+		},
+		{
+			name:             "unknown extension",
+			data:             []byte("%PDF-1."),
+			filename:         "report.notarealextension",
+			expected:         []string{"application/pdf"},
+			valid:            true,
+			extensionMatches: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Verify(bytes.NewBuffer(test.data), test.filename, test.expected...)
+			assert.NilError(t, err)
+			assert.Equal(t, result.Valid, test.valid)
+			assert.Equal(t, result.ExtensionMatches, test.extensionMatches)
+			assert.Equal(t, len(result.Mismatches) == 0, test.valid && test.extensionMatches)
+		})
+	}
+}
+
+func TestVerifyRequiresExpectedType(t *testing.T) {
+	_, err := Verify(bytes.NewBuffer(nil), "")
+	assert.ErrorContains(t, err, "no expected MIME types")
+}
+
+func TestVerifySubClass(t *testing.T) {
+	result, err := Verify(bytes.NewBufferString(`<?xml version="1.0"?><svg>`), "image.svg", "application/xml")
+	assert.NilError(t, err)
+	assert.Assert(t, result.Valid)
+	assert.Equal(t, result.Matched, "application/xml")
+	assert.Assert(t, result.ExtensionMatches)
+}
+
+func TestVerifyAlias(t *testing.T) {
+	result, err := Verify(bytes.NewBufferString("\x1f\x8b\x08"), "archive.gz", "application/x-gzip")
+	assert.NilError(t, err)
+	assert.Assert(t, result.Valid)
+	assert.Equal(t, result.Matched, "application/x-gzip")
+}
+
+func TestVerifyReadError(t *testing.T) {
+	reset := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("%PD"), iotest.ErrReader(reset))
+	result, err := Verify(r, "report.pdf", "application/pdf")
+	assert.Assert(t, errors.Is(err, reset), "got %v", err)
+	assert.Assert(t, !result.Valid)
+}
+
+func TestVerifyBudgetExhausted(t *testing.T) {
+	d := NewDetector(DefaultRules(), WithMaxRead(2))
+	_, err := d.Verify(strings.NewReader("%PDF-1.7\n"), "", "application/pdf")
+	assert.Assert(t, errors.Is(err, ErrBudgetExhausted), "got %v", err)
+
+	d = NewDetector(DefaultRules(), WithMaxRead(512))
+	result, err := d.Verify(strings.NewReader("%PDF-1.7\n"), "", "application/pdf")
+	assert.NilError(t, err)
+	assert.Assert(t, result.Valid)
+}
@@ -0,0 +1,72 @@
+package magic
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// xmlRootBudget is the maximum number of bytes read while looking for the root element of an XML document.
+const xmlRootBudget = 4096
+
+// XMLRootMatcher identifies an XML document by the namespace and local name of its root element. These are the
+// freedesktop "root-XML" rules, which distinguish formats such as SVG and XHTML from generic XML.
+type XMLRootMatcher struct {
+	NamespaceURI string
+	LocalName    string
+	Result       FileType
+	// Origin records where the rule came from, for explanations. It is set by DefaultRules.
+	Origin RuleOrigin
+}
+
+// matchXMLRoot looks up the rule for XML content using the root element of the document, reading no more than
+// xmlRootBudget bytes. It returns nil if the content is not XML, or if no rule matches the root element.
+func (rules *ruleTable) matchXMLRoot(b *bufferedReader) *XMLRootMatcher {
+	if len(rules.xmlRootMatchers) == 0 {
+		return nil
+	}
+	b.MaybeBuffer(xmlRootBudget)
+	data := b.Data()
+	if len(data) > xmlRootBudget {
+		data = data[:xmlRootBudget]
+	}
+	if !looksLikeXML(data) {
+		return nil
+	}
+	root, ok := xmlRootElement(data)
+	if !ok {
+		return nil
+	}
+	for i, m := range rules.xmlRootMatchers {
+		if m.NamespaceURI == root.Space && m.LocalName == root.Local {
+			return &rules.xmlRootMatchers[i]
+		}
+	}
+	return nil
+}
+
+// looksLikeXML reports whether data starts with markup, ignoring any byte order mark and leading whitespace.
+func looksLikeXML(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '<'
+}
+
+// xmlRootElement returns the name of the first element in data, with its namespace resolved.
+func xmlRootElement(data []byte) (xml.Name, bool) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		// only the element names matter, and these are ASCII in practice
+		return input, nil
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, true
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package magic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIdentifyXMLRoot(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		data         string
+		expectedMIME string
+	}{
+		{
+			name:         "SVG",
+			data:         `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<svg xmlns="http://www.w3.org/2000/svg" width="10"/>`,
+			expectedMIME: "image/svg+xml",
+		},
+		{
+			name:         "SVG without declaration",
+			data:         `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`,
+			expectedMIME: "image/svg+xml",
+		},
+		{
+			name: "XHTML with doctype",
+			data: `<?xml version="1.0"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml"><head/></html>`,
+			expectedMIME: "application/xhtml+xml",
+		},
+		{
+			name:         "Atom feed with prefixed namespace",
+			data:         `<?xml version="1.0"?><a:feed xmlns:a="http://www.w3.org/2005/Atom"></a:feed>`,
+			expectedMIME: "application/atom+xml",
+		},
+		{
+			name:         "XSLT",
+			data:         `<?xml version="1.0"?><xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform"/>`,
+			expectedMIME: "application/xslt+xml",
+		},
+		{
+			name:         "generic XML",
+			data:         `<?xml version="1.0"?><note><to>you</to></note>`,
+			expectedMIME: "application/xml",
+		},
+		{
+			name:         "root element beyond the budget",
+			data:         `<?xml version="1.0"?><!--` + strings.Repeat("x", xmlRootBudget) + `--><svg xmlns="http://www.w3.org/2000/svg"/>`,
+			expectedMIME: "application/xml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileType := Identify(bytes.NewBufferString(test.data))
+			assert.Equal(t, fileType.MIME, test.expectedMIME)
+		})
+	}
+}